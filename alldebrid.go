@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types/ad"
+)
+
+const adBaseURL = "https://api.alldebrid.com/v4"
+const adAgent = "godon"
+
+type allDebrid struct {
+	apiKey  string
+	baseURL string
+}
+
+func newAllDebrid(apiKey string) *allDebrid {
+	return &allDebrid{apiKey: apiKey, baseURL: adBaseURL}
+}
+
+func (a *allDebrid) api(path string, params url.Values) string {
+	params.Set("agent", adAgent)
+	params.Set("apikey", a.apiKey)
+	return fmt.Sprintf("%s%s?%s", a.baseURL, path, params.Encode())
+}
+
+func adErr(err *ad.AdError) error {
+	if err == nil {
+		return errors.New("alldebrid: unknown error")
+	}
+	return fmt.Errorf("alldebrid: %s (%s)", err.Message, err.Code)
+}
+
+func (a *allDebrid) CheckCached(ctx context.Context, hashes []string) (map[string]bool, error) {
+	cached := make(map[string]bool, len(hashes))
+	if len(hashes) == 0 {
+		return cached, nil
+	}
+
+	var res ad.InstantResponse
+	if err := debridGet(ctx, a.api("/magnet/instant", url.Values{"magnets[]": hashes}), "", &res); err != nil {
+		return cached, err
+	}
+	if res.Status != "success" {
+		return cached, adErr(res.Error)
+	}
+
+	for _, magnet := range res.Data.Magnets {
+		hash := magnet.Hash
+		if hash == "" {
+			hash = magnet.Magnet
+		}
+		cached[strings.ToLower(hash)] = magnet.Instant
+	}
+	return cached, nil
+}
+
+// FindTorrent reuses a magnet of the account, AllDebrid keeps every file of
+// it.
+func (a *allDebrid) FindTorrent(ctx context.Context, infoHash string, target debridTarget) (string, error) {
+	var res ad.ListResponse
+	if err := debridGet(ctx, a.api("/magnet/status", url.Values{}), "", &res); err != nil {
+		return "", err
+	}
+	if res.Status != "success" {
+		return "", adErr(res.Error)
+	}
+
+	// 4 is "Ready", everything above is an error state
+	for _, magnet := range res.Data.Magnets {
+		if strings.ToLower(magnet.Hash) == infoHash && magnet.StatusCode <= 4 {
+			return strconv.Itoa(magnet.ID), nil
+		}
+	}
+	return "", nil
+}
+
+func (a *allDebrid) AddMagnet(ctx context.Context, magnet string) (string, error) {
+	var res ad.UploadResponse
+	if err := debridGet(ctx, a.api("/magnet/upload", url.Values{"magnets[]": {magnet}}), "", &res); err != nil {
+		return "", err
+	}
+	if res.Status != "success" {
+		return "", adErr(res.Error)
+	}
+	if len(res.Data.Magnets) == 0 {
+		return "", errors.New("alldebrid: magnet not added")
+	}
+	if res.Data.Magnets[0].Error != nil {
+		return "", adErr(res.Data.Magnets[0].Error)
+	}
+
+	return strconv.Itoa(res.Data.Magnets[0].ID), nil
+}
+
+// SelectFiles is a no-op, AllDebrid always downloads the whole torrent.
+func (a *allDebrid) SelectFiles(ctx context.Context, id string, target debridTarget) error {
+	return nil
+}
+
+func (a *allDebrid) WaitReady(ctx context.Context, id string, target debridTarget) (string, error) {
+	deadline := time.Now().Add(debridReadyTimeout)
+
+	for {
+		var res ad.StatusResponse
+		if err := debridGet(ctx, a.api("/magnet/status", url.Values{"id": {id}}), "", &res); err != nil {
+			return "", err
+		}
+		if res.Status != "success" {
+			return "", adErr(res.Error)
+		}
+
+		magnet := res.Data.Magnets
+		// 4 is "Ready", everything above is an error state
+		if magnet.StatusCode == 4 {
+			files := make([]debridFile, 0, len(magnet.Links))
+			for _, link := range magnet.Links {
+				files = append(files, debridFile{Name: link.Filename, Size: link.Size, Link: link.Link})
+			}
+
+			file, found := pickDebridFile(files, target)
+			if !found {
+				return "", errors.New("alldebrid: file not found in the torrent")
+			}
+			return file.Link, nil
+		}
+		if magnet.StatusCode > 4 {
+			return "", fmt.Errorf("alldebrid: torrent %s", magnet.Status)
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("alldebrid: torrent not ready (%s)", magnet.Status)
+		}
+		if !sleepContext(ctx, debridPollInterval) {
+			return "", ctx.Err()
+		}
+	}
+}
+
+func (a *allDebrid) Unrestrict(ctx context.Context, link string) (string, error) {
+	var res ad.UnlockResponse
+	if err := debridGet(ctx, a.api("/link/unlock", url.Values{"link": {link}}), "", &res); err != nil {
+		return "", err
+	}
+	if res.Status != "success" {
+		return "", adErr(res.Error)
+	}
+	if len(res.Data.Link) == 0 {
+		return "", errors.New("alldebrid: no download link")
+	}
+	return res.Data.Link, nil
+}
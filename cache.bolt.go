@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	boltValuesBucket = []byte("values") // key -> boltEntry
+	boltExpiryBucket = []byte("expiry") // expiry + key, soonest first
+)
+
+// boltCache keeps the values in an embedded bbolt file. When full, the
+// entries closest to their expiry go first.
+type boltCache struct {
+	db  *bbolt.DB
+	max int
+}
+
+type boltEntry struct {
+	Data    json.RawMessage `json:"data"`
+	Expires time.Time       `json:"expires,omitempty"`
+}
+
+func openBoltCache(path string, max int) (*boltCache, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{boltValuesBucket, boltExpiryBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltCache{db: db, max: max}, nil
+}
+
+// entries without expiry sort last
+func expiryKey(expires time.Time, key string) []byte {
+	at := uint64(math.MaxInt64)
+	if !expires.IsZero() {
+		at = uint64(expires.UnixNano())
+	}
+	return append(binary.BigEndian.AppendUint64(nil, at), key...)
+}
+
+func (b *boltCache) Get(key string, value any) (bool, error) {
+	var entry boltEntry
+	found := false
+
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(boltValuesBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil || !found {
+		return false, err
+	}
+
+	// expired entries are removed by the next write
+	if !entry.Expires.IsZero() && time.Now().After(entry.Expires) {
+		return false, nil
+	}
+	return true, json.Unmarshal(entry.Data, value)
+}
+
+func (b *boltCache) Set(key string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	entry := boltEntry{Data: data}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		values := tx.Bucket(boltValuesBucket)
+		expiry := tx.Bucket(boltExpiryBucket)
+
+		// the sequence of the expiry bucket counts the entries
+		var previous boltEntry
+		if data := values.Get([]byte(key)); data != nil && json.Unmarshal(data, &previous) == nil {
+			if err := expiry.Delete(expiryKey(previous.Expires, key)); err != nil {
+				return err
+			}
+		} else if err := expiry.SetSequence(expiry.Sequence() + 1); err != nil {
+			return err
+		}
+
+		if err := values.Put([]byte(key), encoded); err != nil {
+			return err
+		}
+		if err := expiry.Put(expiryKey(entry.Expires, key), nil); err != nil {
+			return err
+		}
+
+		return b.evict(tx)
+	})
+}
+
+// evict drops the expired entries and the ones over the size bound.
+func (b *boltCache) evict(tx *bbolt.Tx) error {
+	values := tx.Bucket(boltValuesBucket)
+	expiry := tx.Bucket(boltExpiryBucket)
+
+	over := int(expiry.Sequence()) - b.max
+	now := uint64(time.Now().UnixNano())
+
+	var keys [][]byte
+	cursor := expiry.Cursor()
+	for key, _ := cursor.First(); key != nil && len(key) >= 8; key, _ = cursor.Next() {
+		if over <= 0 && binary.BigEndian.Uint64(key[:8]) > now {
+			break
+		}
+		keys = append(keys, append([]byte(nil), key...))
+		over--
+	}
+
+	for _, key := range keys {
+		if err := values.Delete(key[8:]); err != nil {
+			return err
+		}
+		if err := expiry.Delete(key); err != nil {
+			return err
+		}
+	}
+	return expiry.SetSequence(expiry.Sequence() - uint64(len(keys)))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestBolt(t *testing.T, path string, max int) *boltCache {
+	cache, err := openBoltCache(path, max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cache.db.Close() })
+	return cache
+}
+
+// cached tells which of the keys the cache still answers
+func cached(t *testing.T, cache *boltCache, keys ...string) map[string]bool {
+	found := map[string]bool{}
+	for _, key := range keys {
+		var value int
+		ok, err := cache.Get(key, &value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[key] = ok
+	}
+	return found
+}
+
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	return keys
+}
+
+func TestBoltCacheEviction(t *testing.T) {
+	cache := openTestBolt(t, filepath.Join(t.TempDir(), "cache.db"), 5)
+
+	keys := testKeys(8)
+	for i, key := range keys {
+		if err := cache.Set(key, i, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// same ttl, the oldest are the closest to their expiry
+	found := cached(t, cache, keys...)
+	for i, key := range keys {
+		if want := i >= 3; found[key] != want {
+			t.Errorf("%s cached: %v, want %v", key, found[key], want)
+		}
+	}
+
+	// rewriting a key doesn't count it twice
+	for i := 0; i < 3; i++ {
+		if err := cache.Set(keys[7], 7, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found = cached(t, cache, keys...)
+	for i, key := range keys {
+		if want := i >= 3; found[key] != want {
+			t.Errorf("after rewrites, %s cached: %v, want %v", key, found[key], want)
+		}
+	}
+}
+
+func TestBoltCacheExpiry(t *testing.T) {
+	cache := openTestBolt(t, filepath.Join(t.TempDir(), "cache.db"), 3)
+
+	if err := cache.Set("short", 1, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set("forever", 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if found := cached(t, cache, "short"); !found["short"] {
+		t.Fatal("short missing before its expiry")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if found := cached(t, cache, "short"); found["short"] {
+		t.Error("short answered after its expiry")
+	}
+
+	// the next write drops it, leaving room for two more without evicting
+	// the entry without expiry
+	for _, key := range []string{"a", "b"} {
+		if err := cache.Set(key, 3, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found := cached(t, cache, "forever", "a", "b")
+	for key, ok := range found {
+		if !ok {
+			t.Errorf("%s evicted", key)
+		}
+	}
+
+	// entries without expiry go last when the cache is full
+	if err := cache.Set("c", 4, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if found := cached(t, cache, "forever", "a"); !found["forever"] || found["a"] {
+		t.Errorf("cached %v, want forever kept and a evicted", found)
+	}
+}
+
+func TestBoltCacheReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	keys := testKeys(6)
+
+	cache, err := openBoltCache(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys[:4] {
+		if err := cache.Set(key, i, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cache = openTestBolt(t, path, 4)
+	var value int
+	if ok, err := cache.Get(keys[2], &value); err != nil || !ok || value != 2 {
+		t.Errorf("%s after reopening: %d %v %v, want 2", keys[2], value, ok, err)
+	}
+
+	// the entry count survives too, new entries evict the old ones
+	for i, key := range keys[4:] {
+		if err := cache.Set(key, 4+i, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found := cached(t, cache, keys...)
+	for i, key := range keys {
+		if want := i >= 2; found[key] != want {
+			t.Errorf("%s cached: %v, want %v", key, found[key], want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache keeps JSON encoded values with an expiry. Get fills value and tells
+// if the key was found, a missing or expired key is not an error.
+type Cache interface {
+	Get(key string, value any) (bool, error)
+	Set(key string, value any, ttl time.Duration) error
+}
+
+// backends, set with CACHE_BACKEND and TORRENT_CACHE_BACKEND
+const (
+	cacheRedis     = "redis"     // plain GET/SET, any redis server
+	cacheRedisJSON = "redisjson" // needs the RedisJSON module (Redis Stack)
+	cacheMemory    = "memory"    // in-process LRU, lost on restart
+	cacheBolt      = "bolt"      // embedded bbolt file
+)
+
+const (
+	defaultCachePath       = "./cache.db"
+	defaultCacheMaxEntries = 20000
+)
+
+var (
+	caches   = map[string]Cache{}
+	cachesMu sync.Mutex
+)
+
+// cacheBackend opens a backend once, the layers using the same one share it.
+// A backend that can't be opened falls back to memory.
+func cacheBackend(name string) Cache {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+
+	if cache, ok := caches[name]; ok {
+		return cache
+	}
+
+	cache, err := openCache(name)
+	if err != nil {
+		logError("cache backend failed, using memory", err, "backend", name)
+		if cache = caches[cacheMemory]; cache == nil {
+			cache = newMemoryCache(cacheMaxEntries())
+			caches[cacheMemory] = cache
+		}
+	}
+	caches[name] = cache
+	return cache
+}
+
+func openCache(name string) (Cache, error) {
+	switch name {
+	case cacheRedis, cacheRedisJSON:
+		rdb, err := RedisClient()
+		if err != nil {
+			return nil, err
+		}
+		if err := rdb.Ping(context.Background()).Err(); err != nil {
+			return nil, err
+		}
+		fmt.Printf("OK redis: %s\n", name)
+		if name == cacheRedisJSON {
+			return &redisJSONCache{rdb: rdb}, nil
+		}
+		return &redisCache{rdb: rdb}, nil
+	case cacheMemory:
+		return newMemoryCache(cacheMaxEntries()), nil
+	case cacheBolt:
+		path := os.Getenv("CACHE_PATH")
+		if path == "" {
+			path = defaultCachePath
+		}
+		return openBoltCache(path, cacheMaxEntries())
+	}
+	return nil, fmt.Errorf("unknown cache backend %s", name)
+}
+
+// appCache is the cache of the stream answers and metadata lookups. Redis
+// installs keep RedisJSON unless told otherwise.
+func appCache() Cache {
+	name := strings.ToLower(os.Getenv("CACHE_BACKEND"))
+	if name == "" {
+		name = cacheMemory
+		if os.Getenv("REDIS_URL") != "" {
+			name = cacheRedisJSON
+		}
+	}
+	return cacheBackend(name)
+}
+
+// torrentCache keeps the torrent file lists, on disk by default as they
+// never change.
+func torrentCache() Cache {
+	name := strings.ToLower(os.Getenv("TORRENT_CACHE_BACKEND"))
+	if name == "" {
+		name = cacheBolt
+	}
+	return cacheBackend(name)
+}
+
+// CACHE_MAX_ENTRIES bounds the memory and bolt backends, redis has its own
+// maxmemory policy
+func cacheMaxEntries() int {
+	n, err := strconv.Atoi(os.Getenv("CACHE_MAX_ENTRIES"))
+	if err != nil || n <= 0 {
+		return defaultCacheMaxEntries
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// memoryCache is an in-process LRU. Values are kept encoded so callers never
+// share them.
+type memoryCache struct {
+	mu      sync.Mutex
+	max     int
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	data    []byte
+	expires time.Time
+}
+
+func newMemoryCache(max int) *memoryCache {
+	return &memoryCache{max: max, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (m *memoryCache) Get(key string, value any) (bool, error) {
+	m.mu.Lock()
+	element, ok := m.entries[key]
+	if !ok {
+		m.mu.Unlock()
+		return false, nil
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		m.order.Remove(element)
+		delete(m.entries, key)
+		m.mu.Unlock()
+		return false, nil
+	}
+	m.order.MoveToFront(element)
+	data := entry.data
+	m.mu.Unlock()
+
+	return true, json.Unmarshal(data, value)
+}
+
+func (m *memoryCache) Set(key string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	entry := &memoryEntry{key: key, data: data}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(entry)
+	for m.order.Len() > m.max {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
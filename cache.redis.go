@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisCache stores the values as JSON strings, it works on any redis.
+type redisCache struct {
+	rdb *redis.Client
+}
+
+func (r *redisCache) Get(key string, value any) (bool, error) {
+	data, err := r.rdb.Get(context.Background(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, value)
+}
+
+func (r *redisCache) Set(key string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(context.Background(), key, data, ttl).Err()
+}
+
+// redisJSONCache stores the values as RedisJSON documents, the way the
+// addon always did on Redis Stack.
+type redisJSONCache struct {
+	rdb *redis.Client
+}
+
+func (r *redisJSONCache) Get(key string, value any) (bool, error) {
+	data, err := r.rdb.JSONGet(context.Background(), key, "$").Result()
+	if errors.Is(err, redis.Nil) || (err == nil && data == "") {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// a "$" path answers with the list of matches
+	var matches []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &matches); err != nil {
+		return false, err
+	}
+	if len(matches) == 0 {
+		return false, nil
+	}
+	return true, json.Unmarshal(matches[0], value)
+}
+
+func (r *redisJSONCache) Set(key string, value any, ttl time.Duration) error {
+	ctx := context.Background()
+	if err := r.rdb.JSONSet(ctx, key, "$", value).Err(); err != nil {
+		return err
+	}
+	if ttl > 0 {
+		return r.rdb.Expire(ctx, key, ttl).Err()
+	}
+	return nil
+}
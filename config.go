@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// encrypted tokens are prefixed so plain and encrypted configs can coexist
+const encryptedConfigPrefix = "e."
+
+// the most results a config may ask for, the range of the configure form
+const maxResultsLimit = 200
+
+// defaultConfig returns the settings used when the addon is installed without
+// a configuration token, i.e. the deployment-wide .env values.
+func defaultConfig() types.UserConfig {
+	cfg := types.UserConfig{}
+	cfg.MaxResults, _ = strconv.Atoi(os.Getenv("MAX_RES"))
+	cfg.Template = os.Getenv("STREAM_TEMPLATE")
+	if useDebrid() {
+		cfg.DebridProvider = "realdebrid"
+		cfg.DebridKey = rdDeploymentKey()
+	}
+	return cfg
+}
+
+// userConfig reads the :config route param. Routes without it get the default
+// settings.
+func userConfig(c *fiber.Ctx) (types.UserConfig, error) {
+	token := c.Params("config")
+	if token == "" {
+		return defaultConfig(), nil
+	}
+	return parseConfig(token)
+}
+
+func parseConfig(token string) (types.UserConfig, error) {
+	var cfg types.UserConfig
+
+	encrypted := strings.HasPrefix(token, encryptedConfigPrefix)
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimPrefix(token, encryptedConfigPrefix), "="))
+	if err != nil {
+		return cfg, err
+	}
+
+	if encrypted {
+		raw, err = decryptConfig(raw)
+		if err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, err
+	}
+
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = defaultConfig().MaxResults
+	}
+	// tokens can be written by hand
+	cfg.MaxResults = min(cfg.MaxResults, maxResultsLimit)
+
+	return cfg, nil
+}
+
+// encodeConfig builds the token for a config. It is encrypted when the
+// deployment has a CONFIG_SECRET so debrid keys don't travel in clear text.
+func encodeConfig(cfg types.UserConfig) (string, error) {
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if os.Getenv("CONFIG_SECRET") == "" {
+		return base64.RawURLEncoding.EncodeToString(raw), nil
+	}
+
+	sealed, err := encryptConfig(raw)
+	if err != nil {
+		return "", err
+	}
+	return encryptedConfigPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// configHash is a short stable id of a token, used to keep per-user cache
+// entries apart.
+func configHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+func configCipher() (cipher.AEAD, error) {
+	secret := os.Getenv("CONFIG_SECRET")
+	if secret == "" {
+		return nil, errors.New("CONFIG_SECRET not defined")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptConfig(raw []byte) ([]byte, error) {
+	gcm, err := configCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, raw, nil), nil
+}
+
+func decryptConfig(sealed []byte) ([]byte, error) {
+	gcm, err := configCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("config token too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:embed views/configure.html
+var configurePageHtml string
+
+var configurePage = template.Must(template.New("configure").Parse(configurePageHtml))
+
+type configureOption struct {
+	Value    string
+	Label    string
+	Selected bool
+}
+
+type configurePageData struct {
+	Logo            string
+	Action          string
+	Config          types.UserConfig
+	ExcludeKeywords string
+	Indexers        string
+	Providers       []configureOption
+	Languages       []configureOption
+	Qualities       []configureOption
+	Sorts           []configureOption
+	Templates       []configureOption
+	Codecs          []configureOption
+	Required        []configureOption
+	Weights         []configureWeight
+	InstallURL      template.URL
+	ManifestURL     string
+	Error           string
+}
+
+// configureForm is what the /configure page posts back
+type configureForm struct {
+	DebridProvider  string   `form:"debridProvider"`
+	DebridKey       string   `form:"debridKey"`
+	CachedOnly      bool     `form:"cachedOnly"`
+	Languages       []string `form:"languages"`
+	MaxQuality      string   `form:"maxQuality"`
+	MaxResults      int      `form:"maxResults"`
+	SortBy          string   `form:"sortBy"`
+	ExcludeKeywords string   `form:"excludeKeywords"`
+	Indexers        string   `form:"indexers"`
+	Template        string   `form:"template"`
+	PreferredCodec  string   `form:"preferredCodec"`
+	PreferredSizeGB float64  `form:"preferredSizeGB"`
+	ExcludeCam      bool     `form:"excludeCam"`
+	MaxSizeGB       float64  `form:"maxSizeGB"`
+	RequireLanguage string   `form:"requireLanguage"`
+}
+
+// configureWeight is a ranking weight input, posted as weight_<criterion>
+type configureWeight struct {
+	Criterion string
+	Value     string
+}
+
+var debridProviders = []configureOption{
+	{Value: "realdebrid", Label: "Real-Debrid"},
+	{Value: "alldebrid", Label: "AllDebrid"},
+	{Value: "premiumize", Label: "Premiumize"},
+	{Value: "torbox", Label: "TorBox"},
+}
+
+var configureLanguages = []configureOption{
+	{Value: "french", Label: "French"},
+	{Value: "english", Label: "English"},
+	{Value: "spanish", Label: "Spanish"},
+	{Value: "italian", Label: "Italian"},
+	{Value: "german", Label: "German"},
+	{Value: "japanese", Label: "Japanese"},
+}
+
+var configureQualities = []configureOption{
+	{Value: "", Label: "No limit"},
+	{Value: "4k", Label: "4K"},
+	{Value: "1080p", Label: "1080p"},
+	{Value: "720p", Label: "720p"},
+	{Value: "480p", Label: "480p"},
+}
+
+var configureSorts = []configureOption{
+	{Value: "", Label: "Peers"},
+	{Value: "quality", Label: "Quality"},
+	{Value: "size", Label: "Size"},
+}
+
+var configureTemplates = []configureOption{
+	{Value: "", Label: "Server default"},
+	{Value: "detailed", Label: "Detailed"},
+	{Value: "compact", Label: "Compact"},
+	{Value: "torrentio", Label: "Torrentio like"},
+}
+
+var configureCodecs = []configureOption{
+	{Value: "", Label: "No preference"},
+	{Value: "x265", Label: "x265 / HEVC"},
+	{Value: "x264", Label: "x264 / AVC"},
+	{Value: "AV1", Label: "AV1"},
+}
+
+func withSelected(options []configureOption, selected ...string) []configureOption {
+	res := make([]configureOption, len(options))
+	for i, option := range options {
+		option.Selected = slices.Contains(selected, option.Value)
+		res[i] = option
+	}
+	return res
+}
+
+func renderConfigure(c *fiber.Ctx, data configurePageData) error {
+	data.Logo = "https://upload.wikimedia.org/wikipedia/commons/2/23/Golang.png"
+	data.Action = "/configure"
+	data.ExcludeKeywords = strings.Join(data.Config.ExcludeKeywords, ", ")
+	data.Indexers = strings.Join(data.Config.Indexers, ", ")
+	data.Providers = withSelected(debridProviders, data.Config.DebridProvider)
+	data.Languages = withSelected(configureLanguages, data.Config.Languages...)
+	data.Qualities = withSelected(configureQualities, data.Config.MaxQuality)
+	data.Sorts = withSelected(configureSorts, data.Config.SortBy)
+	data.Templates = withSelected(configureTemplates, data.Config.Template)
+	data.Codecs = withSelected(configureCodecs, data.Config.PreferredCodec)
+	data.Required = withSelected(configureLanguages, data.Config.RequireLanguage)
+
+	data.Weights = make([]configureWeight, 0, len(rankCriteria))
+	for _, criterion := range rankCriteria {
+		weight := configureWeight{Criterion: criterion}
+		if value, ok := data.Config.Weights[criterion]; ok {
+			weight.Value = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		data.Weights = append(data.Weights, weight)
+	}
+
+	var page bytes.Buffer
+	if err := configurePage.Execute(&page, data); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.Status(fiber.StatusOK).Send(page.Bytes())
+}
+
+// configureGet shows the form, prefilled when reached from an installed
+// addon (/:config/configure).
+func configureGet(c *fiber.Ctx) error {
+	data := configurePageData{}
+
+	if c.Params("config") != "" {
+		cfg, err := userConfig(c)
+		if err != nil {
+			data.Error = "Your previous configuration could not be read, please fill the form again."
+		} else {
+			data.Config = cfg
+		}
+	}
+
+	return renderConfigure(c, data)
+}
+
+// configurePost builds the user config from the form and answers with the
+// personalized install links.
+func configurePost(c *fiber.Ctx) error {
+	var form configureForm
+	if err := c.BodyParser(&form); err != nil {
+		return renderConfigure(c, configurePageData{Error: "Invalid form"})
+	}
+
+	cfg := types.UserConfig{
+		DebridProvider: form.DebridProvider,
+		DebridKey:      strings.TrimSpace(form.DebridKey),
+		CachedOnly:     form.CachedOnly,
+		Languages:      form.Languages,
+		MaxQuality:     form.MaxQuality,
+		MaxResults:     min(max(form.MaxResults, 0), maxResultsLimit),
+		SortBy:         form.SortBy,
+		Template:       form.Template,
+
+		PreferredCodec:  form.PreferredCodec,
+		PreferredSizeGB: form.PreferredSizeGB,
+		ExcludeCam:      form.ExcludeCam,
+		MaxSizeGB:       form.MaxSizeGB,
+		RequireLanguage: form.RequireLanguage,
+	}
+	if cfg.DebridProvider == "" {
+		cfg.DebridKey = ""
+		cfg.CachedOnly = false
+	}
+	cfg.ExcludeKeywords = splitList(form.ExcludeKeywords)
+	cfg.Indexers = splitList(form.Indexers)
+
+	// empty weights keep the server defaults
+	for _, criterion := range rankCriteria {
+		value := strings.TrimSpace(c.FormValue("weight_" + criterion))
+		if weight, err := strconv.ParseFloat(value, 64); err == nil && weight >= 0 {
+			if cfg.Weights == nil {
+				cfg.Weights = make(map[string]float64)
+			}
+			cfg.Weights[criterion] = weight
+		}
+	}
+
+	data := configurePageData{Config: cfg}
+
+	if cfg.DebridProvider != "" && cfg.DebridKey == "" {
+		data.Error = "An API key is needed for the debrid provider."
+		return renderConfigure(c, data)
+	}
+
+	token, err := encodeConfig(cfg)
+	if err != nil {
+		data.Error = err.Error()
+		return renderConfigure(c, data)
+	}
+
+	data.ManifestURL = fmt.Sprintf("%s/%s/manifest.json", publicBaseURL(c), token)
+	_, manifestPath, _ := strings.Cut(data.ManifestURL, "://")
+	data.InstallURL = template.URL(fmt.Sprintf("stremio://%s", manifestPath))
+
+	return renderConfigure(c, data)
+}
+
+// publicBaseURL is the url the addon is reached at, BASE_URL when the
+// deployment sets one, the request scheme and host otherwise. Fiber takes the
+// scheme from X-Forwarded-Proto, so TLS proxies in front get https links.
+func publicBaseURL(c *fiber.Ctx) string {
+	if base := strings.TrimRight(os.Getenv("BASE_URL"), "/"); base != "" {
+		return base
+	}
+	return c.BaseURL()
+}
+
+// splitList reads a comma separated form field
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var manifestURL = regexp.MustCompile(`value="(\w+)://addon\.test/([^/"]+)/manifest\.json"`)
+
+func TestConfigurePost(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseURL    string
+		headers    map[string]string
+		maxResults string
+		scheme     string
+		want       int
+	}{
+		{"plain http", "", nil, "30", "http", 30},
+		{"behind a tls proxy", "", map[string]string{"X-Forwarded-Proto": "https"}, "30", "https", 30},
+		{"configured base url", "https://addon.test/", nil, "30", "https", 30},
+		{"too many results", "", nil, "100000", "http", maxResultsLimit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("BASE_URL", test.baseURL)
+
+			form := url.Values{"maxResults": {test.maxResults}, "indexers": {"yggtorrent"}}
+			req := httptest.NewRequest("POST", "http://addon.test/configure", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+
+			match := manifestURL.FindSubmatch(body)
+			if match == nil {
+				t.Fatalf("no manifest url in the page:\n%s", body)
+			}
+			if scheme := string(match[1]); scheme != test.scheme {
+				t.Errorf("scheme %s, want %s", scheme, test.scheme)
+			}
+
+			cfg, err := parseConfig(string(match[2]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.MaxResults != test.want {
+				t.Errorf("max results %d, want %d", cfg.MaxResults, test.want)
+			}
+		})
+	}
+}
+
+// hand-made tokens are bound like the form
+func TestParseConfigMaxResults(t *testing.T) {
+	token, err := encodeConfig(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig
+	cfg.MaxResults = 100000
+	big, err := encodeConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for token, want := range map[string]int{token: testConfig.MaxResults, big: maxResultsLimit} {
+		cfg, err := parseConfig(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.MaxResults != want {
+			t.Errorf("max results %d, want %d", cfg.MaxResults, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+)
+
+// stremio gives up on slow addons, a stream request answers with what it has
+// once its budget is spent
+const defaultRequestBudget = 8 * time.Second
+
+// share of the budget given to the indexer searches, the torrent reads get
+// what is left
+const searchBudgetShare = 0.6
+
+// timeouts of the single calls, cut down to what is left of the budget
+const (
+	metaTimeout   = 5 * time.Second
+	searchTimeout = 10 * time.Second
+	debridTimeout = 5 * time.Second
+)
+
+// answers cut by the budget are kept a short while only
+const partialStreamsTTL = 5 * time.Minute
+
+// REQUEST_BUDGET is in seconds
+func requestBudget() time.Duration {
+	seconds, err := strconv.ParseFloat(os.Getenv("REQUEST_BUDGET"), 64)
+	if err != nil || seconds <= 0 {
+		return defaultRequestBudget
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// timeoutFor bounds the timeout of a call by the deadline of ctx. A spent
+// budget still gets a millisecond so the call fails right away.
+func timeoutFor(ctx context.Context, timeout time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	return max(timeout, time.Millisecond)
+}
+
+// stageContext gives a stage its share of what is left of the budget.
+func stageContext(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(float64(time.Until(deadline))*share))
+}
+
+// sleepContext waits like time.Sleep and tells false when ctx ends first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
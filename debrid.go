@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/magnet"
+	"github.com/gofiber/fiber/v2"
+)
+
+// how long the resolver waits for a provider to get a torrent ready, and
+// between two status checks (shortened by the tests)
+const debridReadyTimeout = 30 * time.Second
+
+var debridPollInterval = 2 * time.Second
+
+// DebridProvider is a debrid service streams can be resolved through.
+type DebridProvider interface {
+	// CheckCached tells for each infohash whether it can be played instantly
+	CheckCached(ctx context.Context, hashes []string) (map[string]bool, error)
+	// FindTorrent returns the id of a torrent of the account with the
+	// infohash that can serve the target, "" when there is none
+	FindTorrent(ctx context.Context, infoHash string, target debridTarget) (string, error)
+	// AddMagnet adds a magnet to the account and returns the provider id
+	AddMagnet(ctx context.Context, magnet string) (string, error)
+	// SelectFiles restricts the download to the wanted file when supported
+	SelectFiles(ctx context.Context, id string, target debridTarget) error
+	// WaitReady polls until the torrent is downloaded and returns the link
+	// of the wanted file, to be given to Unrestrict
+	WaitReady(ctx context.Context, id string, target debridTarget) (string, error)
+	// Unrestrict turns a provider link into a direct download url
+	Unrestrict(ctx context.Context, link string) (string, error)
+}
+
+func newDebridProvider(name string, apiKey string) (DebridProvider, error) {
+	if len(apiKey) == 0 {
+		return nil, fmt.Errorf("no api key for %s", name)
+	}
+
+	switch name {
+	case "", "realdebrid":
+		return newRealDebrid(apiKey), nil
+	case "alldebrid":
+		return newAllDebrid(apiKey), nil
+	case "premiumize":
+		return newPremiumize(apiKey), nil
+	case "torbox":
+		return newTorBox(apiKey), nil
+	}
+	return nil, fmt.Errorf("unknown debrid provider %s", name)
+}
+
+// debridTarget is the file a stream resolves to. Index is 0-based, like the
+// stremio fileIdx; Path and Size find the file in the provider listings that
+// don't keep the torrent order, they are empty when unknown.
+type debridTarget struct {
+	Index int
+	Path  string
+	Size  int64
+}
+
+// newDebridTarget completes what the resolve url tells about a file with the
+// cached file list of its torrent.
+func newDebridTarget(infoHash string, fileIdx int, size int64) debridTarget {
+	target := debridTarget{Index: fileIdx, Size: size}
+	if meta, ok := cachedTorrentMeta(infoHash, ""); ok && fileIdx >= 0 && fileIdx < len(meta.Files) {
+		target.Path = meta.Files[fileIdx].Path
+		target.Size = meta.Files[fileIdx].Size
+	}
+	return target
+}
+
+// resolveStream drives the add -> select -> wait -> unrestrict flow of a
+// provider for a single file and returns the direct download link. A torrent
+// already in the account is reused, every play would add it again otherwise.
+func resolveStream(ctx context.Context, provider DebridProvider, infoHash string, target debridTarget) (string, error) {
+	if len(infoHash) == 0 {
+		return "", fmt.Errorf("infoHash not defined")
+	}
+
+	id, err := provider.FindTorrent(ctx, magnet.NormalizeHash(infoHash), target)
+	if err != nil {
+		logError("torrent lookup failed", upstreamError(stageDebrid, "torrents", 0, err), "infohash", infoHash)
+	}
+
+	if id == "" {
+		id, err = provider.AddMagnet(ctx, magnet.Build(infoHash, "", nil))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := provider.SelectFiles(ctx, id, target); err != nil {
+		return "", err
+	}
+
+	link, err := provider.WaitReady(ctx, id, target)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.Unrestrict(ctx, link)
+}
+
+// debridFile is the part of a provider file listing needed to pick a link
+type debridFile struct {
+	Name string
+	Size int64
+	Link string
+}
+
+// pickDebridFile finds the target in a provider listing, by file name then
+// by size. Listings are not in the torrent order and can leave files out, so
+// the index is never used: a pack whose file can't be told apart fails
+// rather than playing another episode.
+func pickDebridFile(files []debridFile, target debridTarget) (debridFile, bool) {
+	var videos []debridFile
+	for _, file := range files {
+		if isVideo(file.Name) {
+			videos = append(videos, file)
+		}
+	}
+
+	if target.Path != "" {
+		name := strings.ToLower(path.Base(target.Path))
+		var named []debridFile
+		for _, file := range videos {
+			if strings.ToLower(path.Base(file.Name)) == name {
+				named = append(named, file)
+			}
+		}
+		if file, ok := onlyFile(named, target.Size); ok {
+			return file, true
+		}
+	}
+
+	if target.Size > 0 {
+		return onlyFile(videos, target.Size)
+	}
+
+	// nothing known about the file, fine as long as there is one video
+	if len(videos) == 1 {
+		return videos[0], true
+	}
+	return debridFile{}, false
+}
+
+// onlyFile returns the single file of the given size, any size when 0.
+func onlyFile(files []debridFile, size int64) (debridFile, bool) {
+	var matching []debridFile
+	for _, file := range files {
+		if size <= 0 || file.Size == size {
+			matching = append(matching, file)
+		}
+	}
+	if len(matching) != 1 {
+		return debridFile{}, false
+	}
+	return matching[0], true
+}
+
+// debridGet sends a GET to a provider api and decodes the json answer into v.
+func debridGet(ctx context.Context, api string, authorization string, v any) error {
+	request := fiber.Get(api).Timeout(timeoutFor(ctx, debridTimeout))
+	if authorization != "" {
+		request.Set("Authorization", authorization)
+	}
+
+	status, data, errs := request.Bytes()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	if status >= 400 {
+		return debridStatusError(status, data)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("status %d: %w", status, err)
+	}
+	return nil
+}
+
+// debridPost sends a POST to a provider api and decodes the json answer into v.
+func debridPost(ctx context.Context, api string, contentType string, body io.Reader, authorization string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", api, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+	if authorization != "" {
+		req.Header.Add("Authorization", authorization)
+	}
+
+	client := http.Client{Timeout: 2 * debridTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode >= 400 {
+		return debridStatusError(res.StatusCode, data)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("status %d: %w", res.StatusCode, err)
+	}
+	return nil
+}
+
+// debridStatusError is the error of a failed provider call, with the message
+// of the body when the provider gives one.
+func debridStatusError(status int, data []byte) error {
+	var body struct {
+		Message string `json:"message"`
+		Detail  string `json:"detail"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(data, &body)
+
+	for _, message := range []string{body.Detail, body.Message, body.Error.Message} {
+		if message != "" {
+			return fmt.Errorf("status %d: %s", status, message)
+		}
+	}
+	return fmt.Errorf("status %d: %s", status, http.StatusText(status))
+}
+
+// how many hashes go in a single availability call, keeps the urls short
+const debridCacheChunk = 40
+
+var debridTags = map[string]string{
+	"":           "RD",
+	"realdebrid": "RD",
+	"alldebrid":  "AD",
+	"premiumize": "PM",
+	"torbox":     "TB",
+}
+
+// debridTag is the short provider name shown in stream names, e.g. [RD+]
+func debridTag(name string, cached bool) string {
+	if cached {
+		return fmt.Sprintf("[%s+]", debridTags[name])
+	}
+	return fmt.Sprintf("[%s]", debridTags[name])
+}
+
+// checkCachedBatch asks the provider about all the hashes in parallel chunks.
+// Hashes of a failing chunk are reported as not cached.
+func checkCachedBatch(ctx context.Context, provider DebridProvider, hashes []string) map[string]bool {
+	unique := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		hash = strings.ToLower(hash)
+		if hash != "" && !slices.Contains(unique, hash) {
+			unique = append(unique, hash)
+		}
+	}
+
+	cached := make(map[string]bool, len(unique))
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for start := 0; start < len(unique); start += debridCacheChunk {
+		chunk := unique[start:min(start+debridCacheChunk, len(unique))]
+
+		wg.Add(1)
+		go func(chunk []string) {
+			defer wg.Done()
+			res, err := provider.CheckCached(ctx, chunk)
+			if err != nil {
+				logError("cache check failed", upstreamError(stageDebrid, "availability", 0, err))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for hash, ok := range res {
+				cached[hash] = ok
+			}
+		}(chunk)
+	}
+	wg.Wait()
+
+	return cached
+}
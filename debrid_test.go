@@ -0,0 +1,618 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testHash = "0123456789abcdef0123456789abcdef01234567"
+
+// the wanted file is the second one of the pack, the providers list the
+// files in another order and leave the nfo out
+var testTarget = debridTarget{Index: 1, Path: "Show S01/Show.S01E02.1080p.mkv", Size: 200}
+
+func fastPolling(t *testing.T) {
+	interval := debridPollInterval
+	debridPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { debridPollInterval = interval })
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func fakeServer(t *testing.T, routes map[string]http.HandlerFunc) string {
+	mux := http.NewServeMux()
+	for pattern, handler := range routes {
+		mux.HandleFunc(pattern, handler)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestPickDebridFile(t *testing.T) {
+	files := []debridFile{
+		{Name: "Show.S01E03.1080p.mkv", Size: 300, Link: "e03"},
+		{Name: "Show S01/Show.S01E02.1080p.mkv", Size: 200, Link: "e02"},
+		{Name: "Show.S01E01.1080p.mkv", Size: 100, Link: "e01"},
+		{Name: "Show.nfo", Size: 200, Link: "nfo"},
+	}
+
+	tests := []struct {
+		name   string
+		files  []debridFile
+		target debridTarget
+		want   string
+	}{
+		{"by name", files, debridTarget{Index: 0, Path: "Show S01/Show.S01E02.1080p.mkv"}, "e02"},
+		{"by name and size", append(files, debridFile{Name: "Extras/Show.S01E02.1080p.mkv", Size: 50, Link: "extra"}), testTarget, "e02"},
+		{"by size", files, debridTarget{Index: 2, Size: 100}, "e01"},
+		{"single video", files[2:], debridTarget{Index: 5}, "e01"},
+		{"unknown file in a pack", files, debridTarget{Index: 1}, ""},
+		{"no match", files, debridTarget{Index: 1, Path: "Other.mkv", Size: 42}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, found := pickDebridFile(test.files, test.target)
+			if found != (test.want != "") || file.Link != test.want {
+				t.Errorf("got %q (found %v), want %q", file.Link, found, test.want)
+			}
+		})
+	}
+}
+
+// failed calls are errors even when their body reads like an answer
+func TestDebridStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   map[string]any
+		want   string
+	}{
+		{"ok", http.StatusOK, map[string]any{"status": "success"}, ""},
+		{"bad key", http.StatusUnauthorized, map[string]any{"status": "error", "error": map[string]any{"code": "AUTH_BAD_APIKEY", "message": "bad key"}}, "status 401: bad key"},
+		{"detail", http.StatusForbidden, map[string]any{"success": false, "detail": "plan expired"}, "status 403: plan expired"},
+		{"down", http.StatusServiceUnavailable, map[string]any{"status": "success"}, "status 503: Service Unavailable"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api := fakeServer(t, map[string]http.HandlerFunc{
+				"/api": func(w http.ResponseWriter, r *http.Request) {
+					writeJSON(w, test.status, test.body)
+				},
+			}) + "/api"
+
+			var res struct{ Status string }
+			errGet := debridGet(context.Background(), api, "", &res)
+			errPost := debridPost(context.Background(), api, "application/json", strings.NewReader("{}"), "", &res)
+
+			for _, err := range []error{errGet, errPost} {
+				if test.want == "" && err != nil {
+					t.Errorf("error %v", err)
+				}
+				if test.want != "" && (err == nil || err.Error() != test.want) {
+					t.Errorf("error %v, want %q", err, test.want)
+				}
+			}
+		})
+	}
+}
+
+// rdFiles is the RD file list of the test pack with the given file ids
+// selected
+func rdFiles(selected ...int) []map[string]any {
+	files := []map[string]any{}
+	for id, path := range []string{"/Show.S01E01.1080p.mkv", "/Show.S01E02.1080p.mkv", "/Show.S01E03.1080p.mkv"} {
+		file := map[string]any{"id": id + 1, "path": path, "bytes": (id + 1) * 100, "selected": 0}
+		if slices.Contains(selected, id+1) {
+			file["selected"] = 1
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func TestRealDebridResolve(t *testing.T) {
+	fastPolling(t)
+
+	var selected atomic.Value
+	var polls atomic.Int32
+	rd := newRealDebrid("key")
+	rd.baseURL = fakeServer(t, map[string]http.HandlerFunc{
+		"GET /torrents": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"POST /torrents/addMagnet": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer key" || !strings.Contains(r.FormValue("magnet"), testHash) {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad request"})
+				return
+			}
+			writeJSON(w, http.StatusCreated, map[string]any{"id": "T1"})
+		},
+		"GET /torrents/info/T1": func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case selected.Load() == nil:
+				writeJSON(w, http.StatusOK, map[string]any{"id": "T1", "status": "waiting_files_selection"})
+			case polls.Add(1) < 2:
+				writeJSON(w, http.StatusOK, map[string]any{"id": "T1", "status": "downloading"})
+			default:
+				writeJSON(w, http.StatusOK, map[string]any{"id": "T1", "status": "downloaded", "files": rdFiles(2), "links": []string{"https://rd/e02"}})
+			}
+		},
+		"POST /torrents/selectFiles/T1": func(w http.ResponseWriter, r *http.Request) {
+			selected.Store(r.FormValue("files"))
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"POST /unrestrict/link": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]any{"download": "https://cdn/" + strings.TrimPrefix(r.FormValue("link"), "https://rd/")})
+		},
+	})
+
+	link, err := resolveStream(context.Background(), rd, testHash, testTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "https://cdn/e02" {
+		t.Errorf("link %q", link)
+	}
+	// RD file ids are 1-based
+	if selected.Load() != "2" {
+		t.Errorf("selected file %v, want 2", selected.Load())
+	}
+}
+
+func TestRealDebridErrors(t *testing.T) {
+	fastPolling(t)
+
+	tests := []struct {
+		name   string
+		routes map[string]http.HandlerFunc
+		want   string
+	}{
+		{"bad token", map[string]http.HandlerFunc{
+			"POST /torrents/addMagnet": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "bad_token", "error_code": 8})
+			},
+		}, "bad_token"},
+		{"dead torrent", map[string]http.HandlerFunc{
+			"POST /torrents/addMagnet": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusCreated, map[string]any{"id": "T1"})
+			},
+			"GET /torrents/info/T1": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"id": "T1", "status": "magnet_error"})
+			},
+		}, "magnet_error"},
+		{"unknown torrent", map[string]http.HandlerFunc{
+			"POST /torrents/addMagnet": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusCreated, map[string]any{"id": "T1"})
+			},
+			"GET /torrents/info/T1": func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		}, "Not Found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rd := newRealDebrid("key")
+			rd.baseURL = fakeServer(t, test.routes)
+
+			_, err := resolveStream(context.Background(), rd, testHash, testTarget)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+// adLinks is the AllDebrid listing of the test pack, not in torrent order
+var adLinks = []map[string]any{
+	{"link": "https://ad/e03", "filename": "Show.S01E03.1080p.mkv", "size": 300},
+	{"link": "https://ad/e02", "filename": "Show.S01E02.1080p.mkv", "size": 200},
+	{"link": "https://ad/e01", "filename": "Show.S01E01.1080p.mkv", "size": 100},
+}
+
+func TestAllDebridResolve(t *testing.T) {
+	fastPolling(t)
+
+	var polls atomic.Int32
+	ad := newAllDebrid("key")
+	ad.baseURL = fakeServer(t, map[string]http.HandlerFunc{
+		"GET /magnet/upload": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("apikey") != "key" || !strings.Contains(r.URL.Query().Get("magnets[]"), testHash) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "error", "error": map[string]any{"code": "BAD", "message": "bad request"}})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"magnets": []any{map[string]any{"id": 7}}}})
+		},
+		"GET /magnet/status": func(w http.ResponseWriter, r *http.Request) {
+			magnet := map[string]any{"id": 7, "status": "Downloading", "statusCode": 1}
+			if polls.Add(1) > 1 {
+				magnet = map[string]any{"id": 7, "status": "Ready", "statusCode": 4, "links": adLinks}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"magnets": magnet}})
+		},
+		"GET /link/unlock": func(w http.ResponseWriter, r *http.Request) {
+			link := strings.TrimPrefix(r.URL.Query().Get("link"), "https://ad/")
+			writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"link": "https://cdn/" + link}})
+		},
+	})
+
+	link, err := resolveStream(context.Background(), ad, testHash, testTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "https://cdn/e02" {
+		t.Errorf("link %q", link)
+	}
+}
+
+func TestAllDebridErrors(t *testing.T) {
+	fastPolling(t)
+
+	upload := func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"magnets": []any{map[string]any{"id": 7}}}})
+	}
+
+	tests := []struct {
+		name   string
+		routes map[string]http.HandlerFunc
+		want   string
+	}{
+		{"bad key", map[string]http.HandlerFunc{
+			"GET /magnet/upload": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "error", "error": map[string]any{"code": "AUTH_BAD_APIKEY", "message": "bad key"}})
+			},
+		}, "AUTH_BAD_APIKEY"},
+		{"server error", map[string]http.HandlerFunc{
+			"GET /magnet/upload": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "success", "data": map[string]any{"magnets": []any{map[string]any{"id": 7}}}})
+			},
+		}, "status 503"},
+		{"failed download", map[string]http.HandlerFunc{
+			"GET /magnet/upload": upload,
+			"GET /magnet/status": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"magnets": map[string]any{"id": 7, "status": "Upload fail", "statusCode": 7}}})
+			},
+		}, "Upload fail"},
+		{"file not in the listing", map[string]http.HandlerFunc{
+			"GET /magnet/upload": upload,
+			"GET /magnet/status": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"magnets": map[string]any{"id": 7, "status": "Ready", "statusCode": 4, "links": adLinks[:1]}}})
+			},
+		}, "file not found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ad := newAllDebrid("key")
+			ad.baseURL = fakeServer(t, test.routes)
+
+			_, err := resolveStream(context.Background(), ad, testHash, testTarget)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestPremiumizeResolve(t *testing.T) {
+	fastPolling(t)
+
+	var polls atomic.Int32
+	pm := newPremiumize("key")
+	pm.baseURL = fakeServer(t, map[string]http.HandlerFunc{
+		"POST /transfer/create": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("apikey") != "key" || !strings.Contains(r.FormValue("src"), testHash) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "error", "message": "bad request"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"status": "success", "id": "P1"})
+		},
+		"POST /transfer/directdl": func(w http.ResponseWriter, r *http.Request) {
+			if polls.Add(1) == 1 {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "error", "message": "not ready"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"status": "success", "content": []any{
+				map[string]any{"path": "Show S01/Show.S01E01.1080p.mkv", "size": 100, "link": "https://pm/e01"},
+				map[string]any{"path": "Show S01/Show.S01E03.1080p.mkv", "size": 300, "link": "https://pm/e03"},
+				map[string]any{"path": "Show S01/Show.S01E02.1080p.mkv", "size": 200, "link": "https://pm/e02"},
+			}})
+		},
+	})
+
+	link, err := resolveStream(context.Background(), pm, testHash, testTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "https://pm/e02" {
+		t.Errorf("link %q", link)
+	}
+}
+
+func TestPremiumizeErrors(t *testing.T) {
+	fastPolling(t)
+
+	t.Run("bad key", func(t *testing.T) {
+		pm := newPremiumize("key")
+		pm.baseURL = fakeServer(t, map[string]http.HandlerFunc{
+			"POST /transfer/create": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "error", "message": "customer_id and pin param missing or not logged in"})
+			},
+		})
+
+		_, err := resolveStream(context.Background(), pm, testHash, testTarget)
+		if err == nil || !strings.Contains(err.Error(), "not logged in") {
+			t.Errorf("error %v", err)
+		}
+	})
+
+	// a transfer that never gets ready ends with the request
+	t.Run("cancelled", func(t *testing.T) {
+		pm := newPremiumize("key")
+		pm.baseURL = fakeServer(t, map[string]http.HandlerFunc{
+			"POST /transfer/create": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "success", "id": "P1"})
+			},
+			"POST /transfer/directdl": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "error", "message": "not ready"})
+			},
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := resolveStream(ctx, pm, testHash, testTarget)
+		if err == nil || ctx.Err() == nil {
+			t.Errorf("error %v before the deadline", err)
+		}
+	})
+}
+
+func TestTorBoxResolve(t *testing.T) {
+	fastPolling(t)
+
+	var polls atomic.Int32
+	tb := newTorBox("key")
+	tb.baseURL = fakeServer(t, map[string]http.HandlerFunc{
+		"POST /torrents/createtorrent": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer key" || !strings.Contains(r.FormValue("magnet"), testHash) {
+				writeJSON(w, http.StatusOK, map[string]any{"success": false, "detail": "bad request"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": map[string]any{"torrent_id": 42}})
+		},
+		"GET /torrents/mylist": func(w http.ResponseWriter, r *http.Request) {
+			data := map[string]any{"id": 42, "download_state": "downloading"}
+			if polls.Add(1) > 1 {
+				data = map[string]any{"id": 42, "download_state": "cached", "download_present": true, "files": []any{
+					map[string]any{"id": 0, "name": "Show S01/Show.S01E03.1080p.mkv", "size": 300},
+					map[string]any{"id": 5, "name": "Show S01/Show.S01E02.1080p.mkv", "size": 200},
+					map[string]any{"id": 9, "name": "Show S01/Show.S01E01.1080p.mkv", "size": 100},
+				}}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": data})
+		},
+		"GET /torrents/requestdl": func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": "https://cdn/" + query.Get("torrent_id") + "/" + query.Get("file_id")})
+		},
+	})
+
+	link, err := resolveStream(context.Background(), tb, testHash, testTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "https://cdn/42/5" {
+		t.Errorf("link %q", link)
+	}
+}
+
+func TestTorBoxErrors(t *testing.T) {
+	fastPolling(t)
+
+	create := func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": map[string]any{"torrent_id": 42}})
+	}
+
+	tests := []struct {
+		name   string
+		routes map[string]http.HandlerFunc
+		want   string
+	}{
+		{"bad key", map[string]http.HandlerFunc{
+			"POST /torrents/createtorrent": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusUnauthorized, map[string]any{"success": false, "detail": "invalid token"})
+			},
+		}, "invalid token"},
+		{"failed download", map[string]http.HandlerFunc{
+			"POST /torrents/createtorrent": create,
+			"GET /torrents/mylist": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": map[string]any{"id": 42, "download_state": "error"}})
+			},
+		}, "torrent error"},
+		{"no download link", map[string]http.HandlerFunc{
+			"POST /torrents/createtorrent": create,
+			"GET /torrents/mylist": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": map[string]any{"id": 42, "download_present": true, "files": []any{
+					map[string]any{"id": 5, "name": "Show.S01E02.1080p.mkv", "size": 200},
+				}}})
+			},
+			"GET /torrents/requestdl": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"success": false, "detail": "file not found"})
+			},
+		}, "file not found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := newTorBox("key")
+			tb.baseURL = fakeServer(t, test.routes)
+
+			_, err := resolveStream(context.Background(), tb, testHash, testTarget)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+// a torrent already in the account is played without adding it again
+func TestDebridReuse(t *testing.T) {
+	fastPolling(t)
+
+	pmContent := []any{
+		map[string]any{"path": "Show S01/Show.S01E01.1080p.mkv", "size": 100, "link": "https://pm/e01"},
+		map[string]any{"path": "Show S01/Show.S01E02.1080p.mkv", "size": 200, "link": "https://pm/e02"},
+	}
+	tbFiles := []any{
+		map[string]any{"id": 3, "name": "Show S01/Show.S01E01.1080p.mkv", "size": 100},
+		map[string]any{"id": 4, "name": "Show S01/Show.S01E02.1080p.mkv", "size": 200},
+	}
+
+	tests := []struct {
+		name     string
+		provider func(baseURL string) DebridProvider
+		routes   map[string]http.HandlerFunc
+		want     string
+		added    bool
+	}{
+		{"real-debrid", func(baseURL string) DebridProvider {
+			rd := newRealDebrid("key")
+			rd.baseURL = baseURL
+			return rd
+		}, map[string]http.HandlerFunc{
+			"GET /torrents": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, []any{
+					map[string]any{"id": "DEAD", "hash": testHash, "status": "dead"},
+					map[string]any{"id": "OLD", "hash": strings.ToUpper(testHash), "status": "downloaded"},
+				})
+			},
+			"GET /torrents/info/OLD": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"id": "OLD", "status": "downloaded", "files": rdFiles(1, 2, 3), "links": []string{"https://rd/e01", "https://rd/e02", "https://rd/e03"}})
+			},
+			"POST /unrestrict/link": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"download": "https://cdn/" + strings.TrimPrefix(r.FormValue("link"), "https://rd/")})
+			},
+		}, "https://cdn/e02", false},
+		{"real-debrid other file selected", func(baseURL string) DebridProvider {
+			rd := newRealDebrid("key")
+			rd.baseURL = baseURL
+			return rd
+		}, map[string]http.HandlerFunc{
+			"GET /torrents": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, []any{map[string]any{"id": "OLD", "hash": testHash, "status": "downloaded"}})
+			},
+			"GET /torrents/info/OLD": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"id": "OLD", "status": "downloaded", "files": rdFiles(1), "links": []string{"https://rd/e01"}})
+			},
+			"GET /torrents/info/T1": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"id": "T1", "status": "downloaded", "files": rdFiles(2), "links": []string{"https://rd/e02"}})
+			},
+			"POST /unrestrict/link": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"download": "https://cdn/" + strings.TrimPrefix(r.FormValue("link"), "https://rd/")})
+			},
+		}, "https://cdn/e02", true},
+		{"alldebrid", func(baseURL string) DebridProvider {
+			ad := newAllDebrid("key")
+			ad.baseURL = baseURL
+			return ad
+		}, map[string]http.HandlerFunc{
+			"GET /magnet/status": func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("id") == "" {
+					writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"magnets": []any{
+						map[string]any{"id": 6, "hash": testHash, "status": "Upload fail", "statusCode": 7},
+						map[string]any{"id": 7, "hash": testHash, "status": "Ready", "statusCode": 4},
+					}}})
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"magnets": map[string]any{"id": 7, "status": "Ready", "statusCode": 4, "links": adLinks}}})
+			},
+			"GET /link/unlock": func(w http.ResponseWriter, r *http.Request) {
+				link := strings.TrimPrefix(r.URL.Query().Get("link"), "https://ad/")
+				writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": map[string]any{"link": "https://cdn/" + link}})
+			},
+		}, "https://cdn/e02", false},
+		{"premiumize", func(baseURL string) DebridProvider {
+			pm := newPremiumize("key")
+			pm.baseURL = baseURL
+			return pm
+		}, map[string]http.HandlerFunc{
+			"GET /transfer/list": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "success", "transfers": []any{
+					map[string]any{"id": "P0", "status": "finished", "src": "https://example.com/other.torrent"},
+					map[string]any{"id": "P1", "status": "finished", "src": "magnet:?xt=urn:btih:" + testHash + "&dn=Show"},
+				}})
+			},
+			"POST /transfer/directdl": func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{"status": "success", "content": pmContent})
+			},
+		}, "https://pm/e02", false},
+		{"torbox", func(baseURL string) DebridProvider {
+			tb := newTorBox("key")
+			tb.baseURL = baseURL
+			return tb
+		}, map[string]http.HandlerFunc{
+			"GET /torrents/mylist": func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("id") == "" {
+					writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": []any{
+						map[string]any{"id": 41, "hash": "ffffffffffffffffffffffffffffffffffffffff", "download_state": "cached"},
+						map[string]any{"id": 42, "hash": testHash, "download_state": "cached"},
+					}})
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": map[string]any{"id": 42, "download_present": true, "files": tbFiles}})
+			},
+			"GET /torrents/requestdl": func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				writeJSON(w, http.StatusOK, map[string]any{"success": true, "data": "https://cdn/" + query.Get("torrent_id") + "/" + query.Get("file_id")})
+			},
+		}, "https://cdn/42/4", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var added atomic.Bool
+			add := func(w http.ResponseWriter, r *http.Request) {
+				added.Store(true)
+				// the ids of the real-debrid routes, the other providers
+				// never get here
+				writeJSON(w, http.StatusCreated, map[string]any{"id": "T1"})
+			}
+			routes := map[string]http.HandlerFunc{
+				"POST /torrents/addMagnet":      add,
+				"GET /magnet/upload":            add,
+				"POST /transfer/create":         add,
+				"POST /torrents/createtorrent":  add,
+				"POST /torrents/selectFiles/T1": func(w http.ResponseWriter, r *http.Request) {},
+			}
+			for pattern, handler := range test.routes {
+				routes[pattern] = handler
+			}
+
+			link, err := resolveStream(context.Background(), test.provider(fakeServer(t, routes)), testHash, testTarget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if link != test.want {
+				t.Errorf("link %q, want %q", link, test.want)
+			}
+			if added.Load() != test.added {
+				t.Errorf("added %v, want %v", added.Load(), test.added)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/daniwalter001/jackett_fiber/magnet"
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// dedupeKey tells when two results are the same torrent. The infohash when
+// known, in any of its forms, the title and size otherwise as indexers format
+// titles differently.
+func dedupeKey(item types.ItemsParsed) string {
+	if hash := magnet.NormalizeHash(item.InfoHash); hash != "" {
+		return "hash:" + hash
+	}
+	return "title:" + normalizeTitle(item.Title) + ":" + strconv.FormatInt(item.Size, 10)
+}
+
+// normalizeTitle keeps the lowercase words of a title without accents,
+// "Show.S01.FRENCH" and "Show S01 French" are the same.
+func normalizeTitle(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(removeAccents(title)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// removeDuplicates keeps the first result of each torrent, in order, and
+// merges the swarm counts, indexers and trackers of the others into it.
+func removeDuplicates(results []types.ItemsParsed) []types.ItemsParsed {
+	list := make([]types.ItemsParsed, 0, len(results))
+	seen := make(map[string]int, len(results))
+
+	for _, item := range results {
+		if item.Indexer != "" && !slices.Contains(item.Indexers, item.Indexer) {
+			item.Indexers = append(slices.Clone(item.Indexers), item.Indexer)
+		}
+
+		if hash := magnet.NormalizeHash(item.InfoHash); hash != "" {
+			item.InfoHash = hash
+		}
+
+		key := dedupeKey(item)
+		i, found := seen[key]
+		if !found {
+			seen[key] = len(list)
+			list = append(list, item)
+			continue
+		}
+
+		list[i] = mergeDuplicate(list[i], item)
+	}
+	return list
+}
+
+// mergeDuplicate completes a result with a duplicate of it. Indexers see the
+// same swarm at different times, the highest counts are the freshest.
+func mergeDuplicate(item types.ItemsParsed, duplicate types.ItemsParsed) types.ItemsParsed {
+	item.Seeders = maxCount(item.Seeders, duplicate.Seeders)
+	item.Peers = maxCount(item.Peers, duplicate.Peers)
+
+	if item.Size == 0 {
+		item.Size = duplicate.Size
+	}
+	if item.InfoHash == "" {
+		item.InfoHash = duplicate.InfoHash
+	}
+	item.Cached = item.Cached || duplicate.Cached
+
+	item.Indexers = appendMissing(slices.Clone(item.Indexers), duplicate.Indexers...)
+	item.Trackers = appendMissing(slices.Clone(item.Trackers), duplicate.Trackers...)
+	return item
+}
+
+func maxCount(a string, b string) string {
+	av, _ := strconv.Atoi(a)
+	bv, _ := strconv.Atoi(b)
+	if bv > av {
+		return b
+	}
+	return a
+}
+
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		if value != "" && !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
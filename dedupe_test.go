@@ -0,0 +1,95 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+func TestRemoveDuplicates(t *testing.T) {
+	const other = "89abcdef0123456789abcdef0123456789abcdef"
+
+	type want struct {
+		title    string
+		hash     string
+		seeders  string
+		indexers []string
+	}
+
+	tests := []struct {
+		name    string
+		results []types.ItemsParsed
+		want    []want
+	}{
+		{
+			"same hash",
+			[]types.ItemsParsed{
+				{Title: "Show.S01.1080p", InfoHash: testHash, Seeders: "5", Indexer: "yggtorrent"},
+				{Title: "Show S01 1080p WEB", InfoHash: testHash, Seeders: "12", Indexer: "nyaasi"},
+			},
+			[]want{{"Show.S01.1080p", testHash, "12", []string{"yggtorrent", "nyaasi"}}},
+		},
+		{
+			"uppercase hash",
+			[]types.ItemsParsed{
+				{Title: "Show.S01.1080p", InfoHash: testHash, Seeders: "5", Indexer: "yggtorrent"},
+				{Title: "Show.S01.1080p", InfoHash: "0123456789ABCDEF0123456789ABCDEF01234567", Seeders: "3", Indexer: "nyaasi"},
+			},
+			[]want{{"Show.S01.1080p", testHash, "5", []string{"yggtorrent", "nyaasi"}}},
+		},
+		{
+			"base32 and hex of the same hash",
+			[]types.ItemsParsed{
+				{Title: "Show.S01.1080p", InfoHash: "AERUKZ4JVPG66AJDIVTYTK6N54ASGRLH", Seeders: "5", Indexer: "yggtorrent"},
+				{Title: "Show S01", InfoHash: testHash, Seeders: "8", Indexer: "nyaasi"},
+			},
+			[]want{{"Show.S01.1080p", testHash, "8", []string{"yggtorrent", "nyaasi"}}},
+		},
+		{
+			"different hashes",
+			[]types.ItemsParsed{
+				{Title: "Show.S01.1080p", InfoHash: testHash, Size: 100, Seeders: "5", Indexer: "yggtorrent"},
+				{Title: "Show.S01.1080p", InfoHash: other, Size: 100, Seeders: "8", Indexer: "yggtorrent"},
+			},
+			[]want{
+				{"Show.S01.1080p", testHash, "5", []string{"yggtorrent"}},
+				{"Show.S01.1080p", other, "8", []string{"yggtorrent"}},
+			},
+		},
+		{
+			"same title and size without hash",
+			[]types.ItemsParsed{
+				{Title: "Show.S01.FRENCH.1080p", Size: 100, Seeders: "5", Indexer: "yggtorrent"},
+				{Title: "Show S01 French 1080p", Size: 100, Seeders: "8", Indexer: "torrent9"},
+			},
+			[]want{{"Show.S01.FRENCH.1080p", "", "8", []string{"yggtorrent", "torrent9"}}},
+		},
+		{
+			"same title, other size",
+			[]types.ItemsParsed{
+				{Title: "Show.S01.1080p", Size: 100, Seeders: "5", Indexer: "yggtorrent"},
+				{Title: "Show.S01.1080p", Size: 200, Seeders: "8", Indexer: "yggtorrent"},
+			},
+			[]want{
+				{"Show.S01.1080p", "", "5", []string{"yggtorrent"}},
+				{"Show.S01.1080p", "", "8", []string{"yggtorrent"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := removeDuplicates(test.results)
+			if len(got) != len(test.want) {
+				t.Fatalf("%d results, want %d: %+v", len(got), len(test.want), got)
+			}
+			for i, want := range test.want {
+				item := got[i]
+				if item.Title != want.title || item.InfoHash != want.hash || item.Seeders != want.seeders || !slices.Equal(item.Indexers, want.indexers) {
+					t.Errorf("result %d: %s %s %s %v, want %+v", i, item.Title, item.InfoHash, item.Seeders, item.Indexers, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// stages of a stream request, used to tell where an upstream error comes from
+const (
+	stageSearch  = "search"
+	stageMeta    = "meta"
+	stageTorrent = "torrent"
+	stageDebrid  = "debrid"
+)
+
+var errNoResult = errors.New("no result")
+
+// UpstreamError is a failure of one of the services a stream request depends
+// on. A single one only degrades the answer, it never fails the request.
+type UpstreamError struct {
+	Stage  string
+	Source string
+	Status int
+	Err    error
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("%s %s: status %d: %s", e.Stage, e.Source, e.Status, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Stage, e.Source, e.Err)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+func upstreamError(stage string, source string, status int, err error) error {
+	if err == nil && status != 0 {
+		err = errors.New(http.StatusText(status))
+	}
+	if err == nil {
+		err = errNoResult
+	}
+	return &UpstreamError{Stage: stage, Source: source, Status: status, Err: err}
+}
+
+// errIndexer marks search failures of a single indexer, e.g. one the host
+// doesn't know or an unreadable answer. They say nothing about the host.
+var errIndexer = errors.New("indexer error")
+
+func indexerError(source string, err error) error {
+	return upstreamError(stageSearch, source, 0, fmt.Errorf("%w: %w", errIndexer, err))
+}
+
+// hostFailure tells if a search error counts against the host circuit
+// breaker: transport errors and 5xx do, indexer 4xx and bad answers don't.
+func hostFailure(err error) bool {
+	if err == nil || errors.Is(err, errIndexer) {
+		return false
+	}
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) && upstream.Status != 0 {
+		return upstream.Status >= 500
+	}
+	return true
+}
+
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// logError writes an error as a structured log line, with the upstream
+// details when there are some.
+func logError(msg string, err error, args ...any) {
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		args = append(args, "stage", upstream.Stage, "source", upstream.Source)
+		if upstream.Status != 0 {
+			args = append(args, "status", upstream.Status)
+		}
+		err = upstream.Err
+	}
+	args = append(args, "error", err.Error())
+
+	logger.Error(msg, args...)
+}
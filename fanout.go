@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// how many searches and torrent reads run at once for one request
+const (
+	indexerConcurrency = 4
+	queryConcurrency   = 4
+	torrentConcurrency = 8
+)
+
+// fanOut runs work on every item, at most limit at once, and returns the
+// results in the order of the items. Once ctx is done no new work starts and
+// the items left keep the zero result.
+func fanOut[T any, R any](ctx context.Context, items []T, limit int, work func(context.Context, T) R) []R {
+	results := make([]R, len(items))
+	slots := make(chan struct{}, max(limit, 1))
+	wg := sync.WaitGroup{}
+
+launch:
+	for i, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			break launch
+		}
+
+		wg.Add(1)
+		go func(i int, item T) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			// each worker writes its own index, no lock needed
+			results[i] = work(ctx, item)
+		}(i, item)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// flatten joins the results of a fanOut returning lists.
+func flatten[T any](lists [][]T) []T {
+	var all []T
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFanOut(t *testing.T) {
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+
+	var running, most atomic.Int32
+	results := fanOut(context.Background(), items, 4, func(ctx context.Context, item int) int {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return item * 2
+	})
+
+	if most.Load() > 4 {
+		t.Errorf("%d ran at once, the limit is 4", most.Load())
+	}
+	for i, result := range results {
+		if result != i*2 {
+			t.Errorf("result %d is %d, want %d", i, result, i*2)
+		}
+	}
+}
+
+func TestFanOutCancel(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i + 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var started atomic.Int32
+	results := fanOut(ctx, items, 2, func(ctx context.Context, item int) int {
+		started.Add(1)
+		<-ctx.Done()
+		return item
+	})
+
+	// the first two hold the slots until the deadline, nothing starts after
+	if started.Load() != 2 {
+		t.Errorf("%d started, want 2", started.Load())
+	}
+	for i, result := range results[2:] {
+		if result != 0 {
+			t.Errorf("item %d ran after the deadline", i+2)
+		}
+	}
+}
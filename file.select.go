@@ -0,0 +1,147 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/daniwalter001/jackett_fiber/release"
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// torrentFile is a file of a torrent, Index is 0-based like the stremio fileIdx
+type torrentFile struct {
+	Index int
+	Path  string
+	Size  int64
+}
+
+// torrentFiles lists the files of a torrent with their index in it, so
+// files with the same path in two torrents never mix.
+func torrentFiles(item types.ItemsParsed) []torrentFile {
+	files := make([]torrentFile, 0, len(item.TorrentData))
+	for i, file := range item.TorrentData {
+		files = append(files, torrentFile{Index: i, Path: file.Path, Size: file.Size})
+	}
+	return files
+}
+
+// episodeRequest is the episode a series stream is asked for. Kitsu ids also
+// give the absolute numbering, used when Abs is set.
+type episodeRequest struct {
+	Season     int
+	Episode    int
+	Abs        bool
+	AbsSeason  int
+	AbsEpisode int
+}
+
+// folders holding the specials, stremio puts them in season 0
+var specialFolders = []string{"specials", "special", "sp", "sps", "ova", "ovas", "oav"}
+
+// files named after their episode number alone, "05.mkv" or "05 - Title.mkv"
+var bareEpisode = regexp.MustCompile(`^\s*(\d{1,3})(?:\s*[-.]|$)`)
+
+// selectFiles picks the files of a torrent to stream. Movies get the biggest
+// video, series the files of the episode. Several files are returned when
+// the torrent holds more than one copy of the episode.
+func selectFiles(files []torrentFile, torrentTitle string, type_ string, episode episodeRequest) []torrentFile {
+	var videos []torrentFile
+	for _, file := range files {
+		if isVideo(file.Path) && !release.Parse(path.Base(file.Path)).Sample {
+			videos = append(videos, file)
+		}
+	}
+
+	// biggest first, the copies of an episode come in that order too
+	sort.SliceStable(videos, func(i, j int) bool {
+		return videos[i].Size > videos[j].Size
+	})
+
+	if type_ == "movie" {
+		if len(videos) == 0 {
+			return nil
+		}
+		return videos[:1]
+	}
+
+	best := 0
+	var selected []torrentFile
+	for _, file := range videos {
+		score := episodeScore(fileEpisode(file.Path, torrentTitle), episode)
+		if score == 0 || score < best {
+			continue
+		}
+		if score > best {
+			best = score
+			selected = nil
+		}
+		selected = append(selected, file)
+	}
+	return selected
+}
+
+// fileEpisode reads the seasons and episodes of a file. The episode comes from
+// the file name, the season from the closest of the file name, its folders
+// and the torrent title, so "Show S01-S03/Season 2/05.mkv" is S02E05.
+func fileEpisode(filePath string, torrentTitle string) release.Info {
+	dirs := strings.Split(filePath, "/")
+	name := dirs[len(dirs)-1]
+	info := release.Parse(name)
+	if len(info.Episodes) == 0 {
+		if m := bareEpisode.FindStringSubmatch(strings.TrimSuffix(name, path.Ext(name))); m != nil {
+			episode, _ := strconv.Atoi(m[1])
+			info.Episodes = []int{episode}
+		}
+	}
+
+	if len(info.Seasons) > 0 {
+		return info
+	}
+
+	parents := append([]string{torrentTitle}, dirs[:len(dirs)-1]...)
+	for i := len(parents) - 1; i >= 0; i-- {
+		if slices.Contains(specialFolders, strings.ToLower(strings.TrimSpace(parents[i]))) {
+			info.Seasons = []int{0}
+			break
+		}
+
+		parent := release.Parse(parents[i])
+		if len(info.Episodes) == 0 {
+			// episode folders or single episode torrents, Show.S01E05/video.mkv
+			info.Episodes = parent.Episodes
+		}
+		if len(parent.Seasons) > 0 {
+			info.Seasons = parent.Seasons
+			break
+		}
+	}
+
+	info.Absolute = len(info.Episodes) > 0 && len(info.Seasons) == 0
+	return info
+}
+
+// episodeScore tells how sure we are that a file is the wanted episode, 0 when
+// it is not. Files from multi-season folders come after the ones whose season
+// is certain.
+func episodeScore(info release.Info, episode episodeRequest) int {
+	certain := len(info.Seasons) == 1
+
+	switch {
+	case info.HasSeason(episode.Season) && info.HasEpisode(episode.Episode):
+		if certain {
+			return 3
+		}
+		return 2
+	case episode.Abs && info.HasEpisode(episode.AbsEpisode) &&
+		(len(info.Seasons) == 0 || info.HasSeason(episode.AbsSeason) || info.HasSeason(episode.Season)):
+		return 2
+	case len(info.Seasons) == 0 && episode.Season == 1 && info.HasEpisode(episode.Episode):
+		// no season anywhere, episodes are numbered from the start of the show
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSelectFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		paths   []string
+		type_   string
+		episode episodeRequest
+		want    []int
+	}{
+		{
+			"movie",
+			"Movie 2023 1080p",
+			[]string{"Movie.2023.1080p.mkv", "Movie.2023.1080p.sample.mkv", "Movie.nfo"},
+			"movie", episodeRequest{},
+			[]int{0},
+		},
+		{
+			"episode name",
+			"Show S01 1080p",
+			[]string{"Show.S01E01.mkv", "Show.S01E02.mkv", "Show.S01E03.mkv"},
+			"series", episodeRequest{Season: 1, Episode: 2},
+			[]int{1},
+		},
+		{
+			"bare number in a season folder",
+			"Show S01-S03",
+			[]string{"Season 1/05.mkv", "Season 2/04.mkv", "Season 2/05.mkv", "Season 3/05.mkv"},
+			"series", episodeRequest{Season: 2, Episode: 5},
+			[]int{2},
+		},
+		{
+			"bare number in a season pack",
+			"Show S02 1080p",
+			[]string{"04.mkv", "05.mkv", "06.mkv"},
+			"series", episodeRequest{Season: 2, Episode: 5},
+			[]int{1},
+		},
+		{
+			"bare number and episode title",
+			"Show S02 1080p",
+			[]string{"04 - Pilot.mkv", "05 - The End.mkv"},
+			"series", episodeRequest{Season: 2, Episode: 5},
+			[]int{1},
+		},
+		{
+			"bare number of another season",
+			"Show S02 1080p",
+			[]string{"05.mkv"},
+			"series", episodeRequest{Season: 3, Episode: 5},
+			nil,
+		},
+		{
+			"number in the title",
+			"12 Monkeys S01",
+			[]string{"12 Monkeys.mkv"},
+			"series", episodeRequest{Season: 1, Episode: 12},
+			nil,
+		},
+		{
+			"SPxx special",
+			"Show S01-S02 + Specials",
+			[]string{"Show.S01E01.mkv", "Show SP01.mkv", "Show SP02.mkv"},
+			"series", episodeRequest{Season: 0, Episode: 1},
+			[]int{1},
+		},
+		{
+			"named special",
+			"Show Complete",
+			[]string{"Season 1/Show.S01E02.mkv", "Extras/Show - Special 02.mkv"},
+			"series", episodeRequest{Season: 0, Episode: 2},
+			[]int{1},
+		},
+		{
+			"bare number in the specials folder",
+			"Show S01-S02",
+			[]string{"Season 1/03.mkv", "Specials/03.mkv"},
+			"series", episodeRequest{Season: 0, Episode: 3},
+			[]int{1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := make([]torrentFile, len(test.paths))
+			for i, path := range test.paths {
+				// all the same size, the order is kept
+				files[i] = torrentFile{Index: i, Path: path, Size: 1 << 30}
+			}
+
+			var got []int
+			for _, file := range selectFiles(files, test.title, test.type_, test.episode) {
+				got = append(got, file.Index)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("selected %v, want %v", got, test.want)
+			}
+		})
+	}
+}
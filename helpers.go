@@ -9,6 +9,7 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/daniwalter001/jackett_fiber/release"
 	"github.com/daniwalter001/jackett_fiber/types"
 	"golang.org/x/text/runes"
 	"golang.org/x/text/transform"
@@ -25,71 +26,6 @@ func PrettyPrint(i interface{}) string {
 	return string(s)
 }
 
-func containEandS(name string, s string, e string, abs bool, abs_season string, abs_episode string) bool {
-	lower := strings.ToLower(name)
-	return strings.Contains(lower, fmt.Sprintf("s%02se%02s ", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("s%02se%02s.", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("s%02se%02s-", s, e)) ||
-		// -----
-		strings.Contains(lower, fmt.Sprintf("s%se%02s ", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("s%se%02s.", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("s%se%02s-", s, e)) ||
-		// -----
-		strings.Contains(lower, fmt.Sprintf("s%sx%s", s, e)) ||
-		// -----
-		strings.Contains(lower, fmt.Sprintf("s%02s - e%02s ", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("s%02s.e%02s ", s, e)) ||
-		// -----
-		strings.Contains(lower, fmt.Sprintf("s%02se%s ", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("s%02se%s.", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("s%02se%s-", s, e)) ||
-		// -----
-		strings.Contains(lower, fmt.Sprintf("season %s e%s", s, e)) ||
-		// ----- abs
-		(abs &&
-			(strings.Contains(lower, fmt.Sprintf("s%02se%02s", abs_season, abs_episode)) ||
-				strings.Contains(lower, fmt.Sprintf("s%02se%03s", abs_season, abs_episode)) ||
-				strings.Contains(lower, fmt.Sprintf("s%02se%04s", abs_season, abs_episode)) ||
-				strings.Contains(lower, fmt.Sprintf("s%02se%02s", s, abs_episode)) ||
-				strings.Contains(lower, fmt.Sprintf("s%02se%03s", s, abs_episode)) ||
-				strings.Contains(lower, fmt.Sprintf("season %s e%s", s, e)) ||
-				strings.Contains(lower, fmt.Sprintf("s%02se%s-", s, e)) ||
-				strings.Contains(lower, fmt.Sprintf("s%02se%s-", s, e)) ||
-				false))
-}
-
-func containE_S(name string, s string, e string, abs bool, abs_season string, abs_episode string) bool {
-	lower := strings.ToLower(name)
-	return strings.Contains(lower, fmt.Sprintf("s%02s - %02s", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("s%s - %02s", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("season %s - %02s", s, e)) ||
-		strings.Contains(lower, fmt.Sprintf("season %s - %03s", s, e))
-}
-
-func containsAbsoluteE(name string, s string, e string, abs bool, abs_season string, abs_episode string) bool {
-	lower := strings.ToLower(name)
-	return strings.Contains(lower, fmt.Sprintf("%02s ", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf("%03s ", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf("0%s ", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf("%04s ", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf("e%02s ", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf("e%03s ", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf("e0%s ", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf("e%04s ", abs_episode))
-}
-
-func containsAbsoluteE_(name string, s string, e string, abs bool, abs_season string, abs_episode string) bool {
-	lower := strings.ToLower(name)
-	return strings.Contains(lower, fmt.Sprintf(" %02s.", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf(" %03s.", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf(" 0%s.", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf(" %04s.", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf(" %02s-", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf(" %03s-", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf(" 0%s-", abs_episode)) ||
-		strings.Contains(lower, fmt.Sprintf(" %04s-", abs_episode))
-}
-
 func isVideo(name string) bool {
 	lower := strings.ToLower(name)
 
@@ -102,34 +38,50 @@ func isVideo(name string) bool {
 		strings.Contains(lower, ".flv")
 }
 
-func getQuality(name string) string {
-	lower := strings.ToLower(name)
+// qualityRank makes qualities comparable, higher is better and 0 means
+// unknown.
+func qualityRank(name string) int {
+	return release.Parse(name).ResolutionRank()
+}
 
-	if slices.ContainsFunc([]string{"2160", "4k", "uhd"}, func(e string) bool {
-		return strings.Contains(lower, e)
-	}) {
-		return " 🌟4k"
-	}
+var maxQualityRanks = map[string]int{
+	"4k":    4,
+	"1080p": 3,
+	"720p":  2,
+	"480p":  1,
+}
 
-	if slices.ContainsFunc([]string{"1080", "fhd"}, func(e string) bool {
-		return strings.Contains(lower, e)
-	}) {
-		return " 🎥FHD"
+// withinQuality reports whether a title is at or under the user quality cap.
+// Titles with an unknown quality are always kept.
+func withinQuality(name string, maxQuality string) bool {
+	limit, ok := maxQualityRanks[maxQuality]
+	if !ok {
+		return true
 	}
+	return qualityRank(name) <= limit
+}
 
-	if slices.ContainsFunc([]string{"720", "hd"}, func(e string) bool {
-		return strings.Contains(lower, e)
-	}) {
-		return " 📺HD"
-	}
+// release language tags that tell which audio/subtitles a release carries
+var languageTags = map[string][]string{
+	"french":   {"FRENCH", "TRUEFRENCH", "VF", "VFF", "VFQ", "VFI", "VF2", "VOSTFR", "MULTI"},
+	"english":  {"ENGLISH", "MULTI"},
+	"spanish":  {"SPANISH", "LATINO", "MULTI"},
+	"italian":  {"ITALIAN", "MULTI"},
+	"german":   {"GERMAN", "MULTI"},
+	"japanese": {"JAPANESE", "VOSTFR", "MULTI"},
+}
 
-	if slices.ContainsFunc([]string{"480p", "380p", "sd"}, func(e string) bool {
-		return strings.Contains(lower, e)
-	}) {
-		return " 📱SD"
-	}
+func matchLanguages(name string, languages []string) bool {
+	tags := release.Parse(name).Languages
 
-	return ""
+	for _, language := range languages {
+		if slices.ContainsFunc(languageTags[language], func(tag string) bool {
+			return slices.Contains(tags, tag)
+		}) {
+			return true
+		}
+	}
+	return false
 }
 
 // function getSize(size) {
@@ -151,36 +103,17 @@ func getSize(size int) string {
 
 	size_ := "💾 "
 
-	if size/gb > 1 {
-		size_ = size_ + fmt.Sprintf("%.2f GB", float32(size/gb))
-	} else if size/mb > 1 {
-		size_ = size_ + fmt.Sprintf("%.2f MB", float32(size/mb))
+	if size >= gb {
+		size_ = size_ + fmt.Sprintf("%.2f GB", float64(size)/float64(gb))
+	} else if size >= mb {
+		size_ = size_ + fmt.Sprintf("%.2f MB", float64(size)/float64(mb))
 	} else {
-		size_ = size_ + fmt.Sprintf("%.2f KB", float32(size/kb))
+		size_ = size_ + fmt.Sprintf("%.2f KB", float64(size)/float64(kb))
 	}
 	return size_
 
 }
 
-func removeDuplicates(strList []types.ItemsParsed) []types.ItemsParsed {
-	list := []types.ItemsParsed{}
-	for _, item := range strList {
-		if !(contains(list, item)) {
-			list = append(list, item)
-		}
-	}
-	return list
-}
-
-func contains(s []types.ItemsParsed, e types.ItemsParsed) bool {
-	for _, a := range s {
-		if a.Title == e.Title {
-			return true
-		}
-	}
-	return false
-}
-
 func filter[T any](slice []T, cb func(T) bool) (ret []T) {
 	for _, v := range slice {
 		if cb(v) {
@@ -201,11 +134,22 @@ func getServers() []types.Server {
 	fileScanner := bufio.NewScanner(readFile)
 	fileScanner.Split(bufio.ScanLines)
 
+	// host|apikey[|backend]
 	for fileScanner.Scan() {
-		servers = append(servers, types.Server{
-			Host:   strings.Split(fileScanner.Text(), "|")[0],
-			ApiKey: strings.Split(fileScanner.Text(), "|")[1],
-		})
+		fields := strings.Split(fileScanner.Text(), "|")
+		if len(fields) < 2 {
+			continue
+		}
+
+		server := types.Server{
+			Host:    fields[0],
+			ApiKey:  fields[1],
+			Backend: "jackett",
+		}
+		if len(fields) > 2 && strings.TrimSpace(fields[2]) == "prowlarr" {
+			server.Backend = "prowlarr"
+		}
+		servers = append(servers, server)
 	}
 
 	return servers
@@ -215,7 +159,7 @@ func removeAccents(s string) string {
 	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
 	output, _, e := transform.String(t, s)
 	if e != nil {
-		panic(e)
+		return s
 	}
 	return output
 }
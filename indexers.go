@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+const defaultIndexer = "yggtorrent"
+
+var defaultCategories = map[string]string{
+	"movie": "2000",
+	"":      "5000",
+}
+
+// category maps and caps of the indexers we know about, any other Jackett
+// indexer id works with the default categories
+var knownIndexers = map[string]types.Indexer{
+	"yggtorrent": {
+		ID:         "yggtorrent",
+		Categories: map[string]string{"movie": "2000", "anime": "5070", "": "5000"},
+	},
+	"nyaasi": {
+		ID:         "nyaasi",
+		Categories: map[string]string{"movie": "2000,5070", "": "5000,5070"},
+		MaxResults: 50,
+	},
+	"1337x": {
+		ID:         "1337x",
+		Categories: map[string]string{"movie": "2000", "anime": "5070", "": "5000"},
+		MaxResults: 50,
+	},
+	"all": {
+		ID:         "all",
+		Categories: map[string]string{"movie": "2000", "anime": "5070", "": "5000"},
+		MaxResults: 100,
+	},
+}
+
+// parseIndexer reads an "id" or "id:cap" entry
+func parseIndexer(entry string) (types.Indexer, bool) {
+	id, limit, _ := strings.Cut(strings.TrimSpace(entry), ":")
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return types.Indexer{}, false
+	}
+
+	indexer, ok := knownIndexers[id]
+	if !ok {
+		indexer = types.Indexer{ID: id, Categories: defaultCategories}
+	}
+
+	if maxResults, err := strconv.Atoi(strings.TrimSpace(limit)); err == nil && maxResults > 0 {
+		indexer.MaxResults = maxResults
+	}
+
+	return indexer, true
+}
+
+// resolveIndexers returns the indexers to query: the user ones, else the
+// INDEXERS env (comma separated "id" or "id:cap"), else the legacy
+// OVERRIDE_API_URL, else yggtorrent.
+func resolveIndexers(cfg types.UserConfig) []types.Indexer {
+	entries := cfg.Indexers
+	if len(entries) == 0 && os.Getenv("INDEXERS") != "" {
+		entries = strings.Split(os.Getenv("INDEXERS"), ",")
+	}
+
+	indexers := make([]types.Indexer, 0, len(entries))
+	for _, entry := range entries {
+		if indexer, ok := parseIndexer(entry); ok {
+			indexers = append(indexers, indexer)
+		}
+	}
+	if len(indexers) > 0 {
+		return indexers
+	}
+
+	if override := os.Getenv("OVERRIDE_API_URL"); override != "" {
+		return []types.Indexer{{ID: indexerFromPath(override), Path: override}}
+	}
+
+	indexer, _ := parseIndexer(defaultIndexer)
+	return []types.Indexer{indexer}
+}
+
+// indexerFromPath extracts the id of a /api/v2.0/indexers/<id>/results path
+func indexerFromPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if part == "indexers" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return "override"
+}
+
+// categoryFor returns the torznab categories of an indexer for a stremio type
+func categoryFor(indexer types.Indexer, type_ string) string {
+	if category, ok := indexer.Categories[type_]; ok {
+		return category
+	}
+	return indexer.Categories[""]
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/magnet"
+	"github.com/daniwalter001/jackett_fiber/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type jackett struct {
+	server types.Server
+}
+
+func (j *jackett) torznab(indexer types.Indexer) string {
+	return fmt.Sprintf("%s/api/v2.0/indexers/%s/results/torznab/api", j.server.Host, indexer.ID)
+}
+
+func (j *jackett) Caps(ctx context.Context, indexer types.Indexer) (types.TorznabCaps, error) {
+	var caps types.TorznabCaps
+
+	request := fiber.Get(fmt.Sprintf("%s?t=caps&apikey=%s", j.torznab(indexer), j.server.ApiKey)).Timeout(timeoutFor(ctx, 10*time.Second))
+
+	status, data, errs := request.Bytes()
+	if len(errs) > 0 {
+		return caps, upstreamError(stageSearch, indexer.ID, 0, errs[0])
+	}
+	if status >= 400 {
+		return caps, upstreamError(stageSearch, indexer.ID, status, nil)
+	}
+
+	if err := xml.Unmarshal(data, &caps); err != nil {
+		return caps, indexerError(indexer.ID, err)
+	}
+	return caps, nil
+}
+
+func (j *jackett) Search(ctx context.Context, query types.SearchQuery, type_ string, indexer types.Indexer) ([]types.ItemsParsed, error) {
+	params := torznabParams(query)
+	params.Set("apikey", j.server.ApiKey)
+
+	api := fmt.Sprintf("%s?cache=false&cat=%s&%s", j.torznab(indexer), categoryFor(indexer, type_), params.Encode())
+
+	if indexer.Path != "" {
+		api = fmt.Sprintf("%s%s&%s", j.server.Host, indexer.Path, params.Encode())
+	}
+
+	request := fiber.Get(api).Timeout(timeoutFor(ctx, searchTimeout))
+
+	status, data, errs := request.Bytes()
+	if len(errs) > 0 {
+		return nil, upstreamError(stageSearch, indexer.ID, 0, errs[0])
+	}
+	fmt.Printf("Status code: %d\n", status)
+	if status >= 400 {
+		return nil, upstreamError(stageSearch, indexer.ID, status, nil)
+	}
+
+	return parseTorznab(data, indexer)
+}
+
+// torznabParams turns a query into torznab url params
+func torznabParams(query types.SearchQuery) url.Values {
+	params := url.Values{}
+
+	switch query.Type {
+	case "tvsearch", "movie":
+		params.Set("t", query.Type)
+		params.Set("imdbid", query.ImdbID)
+		if query.Season > 0 {
+			params.Set("season", strconv.Itoa(query.Season))
+		}
+		if query.Episode > 0 {
+			params.Set("ep", strconv.Itoa(query.Episode))
+		}
+	default:
+		params.Set("q", removeAccents(query.Query))
+	}
+
+	return params
+}
+
+// parseTorznab reads a torznab rss answer, Jackett and Prowlarr share it.
+func parseTorznab(data []byte, indexer types.Indexer) ([]types.ItemsParsed, error) {
+	var res types.JackettRssReponse
+
+	xmlErr := xml.Unmarshal(data, &res)
+
+	if xmlErr != nil {
+		return nil, indexerError(indexer.ID, xmlErr)
+	}
+
+	items := res.Channel.Item
+	var parsedItems []types.ItemsParsed
+	for i := 0; i < len(items); i++ {
+		var a types.ItemsParsed
+		a.Title = items[i].Title
+		a.Link = items[i].Enclosure.URL
+		a.Tracker = items[i].Jackettindexer.Text
+		a.Indexer = indexer.ID
+		a.MagnetURI = items[i].Link
+		a.Size, _ = strconv.ParseInt(items[i].Size, 10, 64)
+		attr := items[i].Attr
+		for ii := 0; ii < len(attr); ii++ {
+			if attr[ii].Name == "seeders" {
+				a.Seeders = attr[ii].Value
+			}
+			if attr[ii].Name == "peers" {
+				a.Peers = attr[ii].Value
+			}
+			if attr[ii].Name == "infohash" {
+				a.InfoHash = attr[ii].Value
+			}
+			if attr[ii].Name == "magneturl" && magnet.IsMagnet(attr[ii].Value) {
+				a.MagnetURI = attr[ii].Value
+			}
+		}
+		parsedItems = append(parsedItems, withMagnet(a))
+
+		if indexer.MaxResults > 0 && len(parsedItems) >= indexer.MaxResults {
+			break
+		}
+	}
+
+	// fmt.Println(PrettyPrint(parsedItems))
+
+	return parsedItems, nil
+}
@@ -0,0 +1,161 @@
+// Package magnet parses and builds magnet links, with v1 (btih) and v2
+// (btmh) infohashes, e.g. "magnet:?xt=urn:btih:<hash>&dn=Name&tr=udp://...".
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const prefix = "magnet:?"
+
+// sha2-256 multihash header of the v2 infohashes, code 0x12 and length 0x20
+const sha256Multihash = "1220"
+
+// Magnet is what a magnet link tells. Infohashes are lowercase hex, empty
+// when missing.
+type Magnet struct {
+	InfoHash   string // v1, 40 hex chars
+	InfoHashV2 string // v2, 64 hex chars without the multihash header
+	Name       string // dn
+	Size       int64  // xl
+	Trackers   []string
+	WebSeeds   []string
+}
+
+var ErrNotMagnet = errors.New("not a magnet link")
+var ErrNoInfoHash = errors.New("magnet without infohash")
+
+// IsMagnet tells if the link is a magnet, the torrent links of the indexers
+// are plain http urls.
+func IsMagnet(link string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(link)), prefix)
+}
+
+// Parse reads a magnet link. Hybrid torrents carry both a btih and a btmh
+// xt, any of the two is enough.
+func Parse(link string) (Magnet, error) {
+	link = strings.TrimSpace(link)
+	if !IsMagnet(link) {
+		return Magnet{}, ErrNotMagnet
+	}
+
+	// read in order, the first name wins and the trackers keep their order
+	var m Magnet
+	for _, param := range strings.Split(link[len(prefix):], "&") {
+		key, value, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		// several topics are numbered, xt.1, xt.2...
+		key, _, _ = strings.Cut(strings.ToLower(key), ".")
+
+		switch key {
+		case "xt":
+			m.setTopic(value)
+		case "dn":
+			if m.Name == "" {
+				m.Name = value
+			}
+		case "xl":
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > 0 {
+				m.Size = size
+			}
+		case "tr":
+			m.Trackers = appendNew(m.Trackers, value)
+		case "ws":
+			m.WebSeeds = appendNew(m.WebSeeds, value)
+		}
+	}
+
+	if m.InfoHash == "" && m.InfoHashV2 == "" {
+		return Magnet{}, ErrNoInfoHash
+	}
+	return m, nil
+}
+
+func (m *Magnet) setTopic(topic string) {
+	lower := strings.ToLower(topic)
+
+	switch {
+	case strings.HasPrefix(lower, "urn:btih:"):
+		if hash := NormalizeHash(topic[len("urn:btih:"):]); hash != "" && m.InfoHash == "" {
+			m.InfoHash = hash
+		}
+	case strings.HasPrefix(lower, "urn:btmh:"+sha256Multihash):
+		hash := lower[len("urn:btmh:"+sha256Multihash):]
+		if len(hash) == 64 && isHex(hash) && m.InfoHashV2 == "" {
+			m.InfoHashV2 = hash
+		}
+	}
+}
+
+// NormalizeHash turns a v1 infohash, hex or base32, into lowercase hex. It
+// returns "" for anything else.
+func NormalizeHash(hash string) string {
+	hash = strings.TrimSpace(hash)
+
+	switch len(hash) {
+	case 40:
+		if isHex(hash) {
+			return strings.ToLower(hash)
+		}
+	case 32:
+		data, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err == nil && len(data) == 20 {
+			return hex.EncodeToString(data)
+		}
+	}
+	return ""
+}
+
+// String builds the magnet link back, infohashes first.
+func (m Magnet) String() string {
+	var params []string
+	if m.InfoHash != "" {
+		params = append(params, "xt=urn:btih:"+m.InfoHash)
+	}
+	if m.InfoHashV2 != "" {
+		params = append(params, "xt=urn:btmh:"+sha256Multihash+m.InfoHashV2)
+	}
+	if m.Name != "" {
+		params = append(params, "dn="+url.QueryEscape(m.Name))
+	}
+	if m.Size > 0 {
+		params = append(params, "xl="+strconv.FormatInt(m.Size, 10))
+	}
+	for _, tracker := range m.Trackers {
+		params = append(params, "tr="+url.QueryEscape(tracker))
+	}
+	for _, seed := range m.WebSeeds {
+		params = append(params, "ws="+url.QueryEscape(seed))
+	}
+	return prefix + strings.Join(params, "&")
+}
+
+// Build makes the magnet of a v1 infohash, name and trackers are optional.
+func Build(infoHash string, name string, trackers []string) string {
+	return Magnet{InfoHash: NormalizeHash(infoHash), Name: name, Trackers: trackers}.String()
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func appendNew(values []string, value string) []string {
+	value = strings.TrimSpace(value)
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	if value == "" {
+		return values
+	}
+	return append(values, value)
+}
@@ -0,0 +1,98 @@
+package magnet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const (
+	hash   = "0123456789abcdef0123456789abcdef01234567"
+	hashV2 = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want Magnet
+		err  error
+	}{
+		{"hex", "magnet:?xt=urn:btih:" + hash + "&dn=Show.S01E02", Magnet{InfoHash: hash, Name: "Show.S01E02"}, nil},
+		{"uppercase hex", "magnet:?xt=urn:btih:0123456789ABCDEF0123456789ABCDEF01234567", Magnet{InfoHash: hash}, nil},
+		{"base32", "magnet:?xt=urn:btih:AERUKZ4JVPG66AJDIVTYTK6N54ASGRLH", Magnet{InfoHash: hash}, nil},
+		{"lowercase base32", "magnet:?xt=urn:btih:aeruKZ4JVPG66AJDIVTYTK6N54ASGRLH", Magnet{InfoHash: hash}, nil},
+		{"uppercase prefix and keys", "MAGNET:?XT=URN:BTIH:" + hash + "&DN=Show", Magnet{InfoHash: hash, Name: "Show"}, nil},
+		{"btmh only", "magnet:?xt=urn:btmh:1220" + hashV2, Magnet{InfoHashV2: hashV2}, nil},
+		{"hybrid", "magnet:?xt=urn:btih:" + hash + "&xt=urn:btmh:1220" + hashV2, Magnet{InfoHash: hash, InfoHashV2: hashV2}, nil},
+		{"numbered topics", "magnet:?xt.1=urn:btih:" + hash + "&xt.2=urn:btmh:1220" + hashV2, Magnet{InfoHash: hash, InfoHashV2: hashV2}, nil},
+		{
+			"repeated trackers",
+			"magnet:?xt=urn:btih:" + hash + "&tr=udp%3A%2F%2Fa.test%3A1337&tr=udp://b.test:80&tr=udp%3A%2F%2Fa.test%3A1337",
+			Magnet{InfoHash: hash, Trackers: []string{"udp://a.test:1337", "udp://b.test:80"}},
+			nil,
+		},
+		{"size and web seed", "magnet:?xt=urn:btih:" + hash + "&xl=1024&ws=https%3A%2F%2Fseed.test%2Ff", Magnet{InfoHash: hash, Size: 1024, WebSeeds: []string{"https://seed.test/f"}}, nil},
+		{"first name wins", "magnet:?xt=urn:btih:" + hash + "&dn=First&dn=Second", Magnet{InfoHash: hash, Name: "First"}, nil},
+		{"missing xt", "magnet:?dn=Show&tr=udp://a.test:1337", Magnet{}, ErrNoInfoHash},
+		{"bad hash", "magnet:?xt=urn:btih:0123", Magnet{}, ErrNoInfoHash},
+		{"other multihash", "magnet:?xt=urn:btmh:1320" + hashV2, Magnet{}, ErrNoInfoHash},
+		{"http link", "https://indexer.test/dl/1", Magnet{}, ErrNotMagnet},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.link)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Parse(%q)\n got %+v\nwant %+v", test.link, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHash(t *testing.T) {
+	tests := map[string]string{
+		hash: hash,
+		"0123456789ABCDEF0123456789ABCDEF01234567": hash,
+		"AERUKZ4JVPG66AJDIVTYTK6N54ASGRLH":         hash,
+		" " + hash + " ":                           hash,
+		"0123456789abcdef0123456789abcdef0123456z": "",
+		"AERUKZ4JVPG66AJDIVTYTK6N54ASGRL1":         "",
+		"":                                         "",
+	}
+
+	for given, want := range tests {
+		if got := NormalizeHash(given); got != want {
+			t.Errorf("NormalizeHash(%q) = %q, want %q", given, got, want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	magnets := []Magnet{
+		{InfoHash: hash},
+		{InfoHash: hash, Name: "Show S01 & more = 100%", Trackers: []string{"udp://a.test:1337/announce", "https://b.test/announce?key=1"}},
+		{InfoHashV2: hashV2, Size: 1 << 30, WebSeeds: []string{"https://seed.test/f"}},
+		{InfoHash: hash, InfoHashV2: hashV2, Name: "Hybrid"},
+	}
+
+	for _, m := range magnets {
+		got, err := Parse(m.String())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", m.String(), err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("round trip of %q\n got %+v\nwant %+v", m.String(), got, m)
+		}
+	}
+
+	// Build takes any hash form
+	built, err := Parse(Build("AERUKZ4JVPG66AJDIVTYTK6N54ASGRLH", "Show", []string{"udp://a.test:1337"}))
+	want := Magnet{InfoHash: hash, Name: "Show", Trackers: []string{"udp://a.test:1337"}}
+	if err != nil || !reflect.DeepEqual(built, want) {
+		t.Errorf("Build: got %+v (%v), want %+v", built, err, want)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// a bad token must not look like a broken addon to stremio
+func TestStreamInvalidConfig(t *testing.T) {
+	var logs bytes.Buffer
+	previous := logger
+	logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	t.Cleanup(func() { logger = previous })
+
+	for _, token := range []string{"not-a-token!", encryptedConfigPrefix + "AAAA"} {
+		logs.Reset()
+
+		res, err := app.Test(httptest.NewRequest("GET", "/"+token+"/stream/series/"+testImdb+":1:2.json", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+
+		if res.StatusCode != 200 {
+			t.Errorf("%s: status %d, want 200", token, res.StatusCode)
+		}
+		if got := strings.TrimSpace(string(body)); got != `{"streams":[]}` {
+			t.Errorf("%s: body %s", token, got)
+		}
+		if !strings.Contains(logs.String(), `"msg":"invalid config"`) {
+			t.Errorf("%s: invalid config not logged: %q", token, logs.String())
+		}
+	}
+}
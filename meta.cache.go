@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// names, years and kitsu mappings barely change, they are kept a day
+const metaCacheTTL = 24 * time.Hour
+
+type metaEntry struct {
+	Name string `json:"name"`
+	Year string `json:"year"`
+}
+
+// getMetaCached is getMeta through the cache, failures are not cached.
+func getMetaCached(ctx context.Context, id string, type_ string) (string, string, error) {
+	key := fmt.Sprintf("meta:%s:%s", type_, id)
+
+	var entry metaEntry
+	if found, err := appCache().Get(key, &entry); err == nil && found {
+		return entry.Name, entry.Year, nil
+	}
+
+	name, year, err := getMeta(ctx, id, type_)
+	if err != nil {
+		return name, year, err
+	}
+
+	if errCache := appCache().Set(key, metaEntry{Name: name, Year: year}, metaCacheTTL); errCache != nil {
+		logError("cache write failed", errCache, "key", key)
+	}
+	return name, year, nil
+}
+
+// getImdbFromKitsuCached is getImdbFromKitsu through the cache.
+func getImdbFromKitsuCached(ctx context.Context, id string) ([]string, error) {
+	key := "kitsu:" + id
+
+	var ids []string
+	if found, err := appCache().Get(key, &ids); err == nil && found && len(ids) > 0 {
+		return ids, nil
+	}
+
+	ids, err := getImdbFromKitsu(ctx, id)
+	if err != nil {
+		return ids, err
+	}
+
+	if errCache := appCache().Set(key, ids, metaCacheTTL); errCache != nil {
+		logError("cache write failed", errCache, "key", key)
+	}
+	return ids, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/magnet"
+	"github.com/daniwalter001/jackett_fiber/types/pm"
+)
+
+const pmBaseURL = "https://www.premiumize.me/api"
+
+type premiumize struct {
+	apiKey  string
+	baseURL string
+
+	// directdl works on the magnet, not on the transfer id
+	mu      sync.Mutex
+	magnets map[string]string
+}
+
+func newPremiumize(apiKey string) *premiumize {
+	return &premiumize{apiKey: apiKey, baseURL: pmBaseURL, magnets: make(map[string]string)}
+}
+
+func (p *premiumize) api(path string) string {
+	return fmt.Sprintf("%s%s?apikey=%s", p.baseURL, path, url.QueryEscape(p.apiKey))
+}
+
+func (p *premiumize) post(ctx context.Context, path string, params url.Values, v any) error {
+	return debridPost(ctx, p.api(path), "application/x-www-form-urlencoded", strings.NewReader(params.Encode()), "", v)
+}
+
+func (p *premiumize) CheckCached(ctx context.Context, hashes []string) (map[string]bool, error) {
+	cached := make(map[string]bool, len(hashes))
+	if len(hashes) == 0 {
+		return cached, nil
+	}
+
+	var res pm.CacheCheckResponse
+	if err := debridGet(ctx, fmt.Sprintf("%s&%s", p.api("/cache/check"), url.Values{"items[]": hashes}.Encode()), "", &res); err != nil {
+		return cached, err
+	}
+	if res.Status != "success" {
+		return cached, fmt.Errorf("premiumize: %s", res.Message)
+	}
+
+	// answers come in the same order as the items
+	for i, hash := range hashes {
+		cached[strings.ToLower(hash)] = i < len(res.Response) && res.Response[i]
+	}
+	return cached, nil
+}
+
+// FindTorrent reuses a transfer of the account, Premiumize keeps every file
+// of it.
+func (p *premiumize) FindTorrent(ctx context.Context, infoHash string, target debridTarget) (string, error) {
+	var res pm.TransferListResponse
+	if err := debridGet(ctx, p.api("/transfer/list"), "", &res); err != nil {
+		return "", err
+	}
+	if res.Status != "success" {
+		return "", fmt.Errorf("premiumize: %s", res.Message)
+	}
+
+	for _, transfer := range res.Transfers {
+		parsed, err := magnet.Parse(transfer.Src)
+		if err != nil || parsed.InfoHash != infoHash || transfer.Status == "error" {
+			continue
+		}
+
+		p.mu.Lock()
+		p.magnets[transfer.ID] = transfer.Src
+		p.mu.Unlock()
+		return transfer.ID, nil
+	}
+	return "", nil
+}
+
+func (p *premiumize) AddMagnet(ctx context.Context, magnet string) (string, error) {
+	var res pm.TransferCreateResponse
+	if err := p.post(ctx, "/transfer/create", url.Values{"src": {magnet}}, &res); err != nil {
+		return "", err
+	}
+	if res.Status != "success" {
+		return "", fmt.Errorf("premiumize: %s", res.Message)
+	}
+
+	p.mu.Lock()
+	p.magnets[res.ID] = magnet
+	p.mu.Unlock()
+
+	return res.ID, nil
+}
+
+// SelectFiles is a no-op, Premiumize always downloads the whole torrent.
+func (p *premiumize) SelectFiles(ctx context.Context, id string, target debridTarget) error {
+	return nil
+}
+
+func (p *premiumize) WaitReady(ctx context.Context, id string, target debridTarget) (string, error) {
+	p.mu.Lock()
+	magnet, ok := p.magnets[id]
+	p.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("premiumize: unknown transfer %s", id)
+	}
+
+	deadline := time.Now().Add(debridReadyTimeout)
+
+	for {
+		var res pm.DirectDlResponse
+		if err := p.post(ctx, "/transfer/directdl", url.Values{"src": {magnet}}, &res); err != nil {
+			return "", err
+		}
+
+		// directdl only answers once the content is in the cloud
+		if res.Status == "success" && len(res.Content) > 0 {
+			files := make([]debridFile, 0, len(res.Content))
+			for _, content := range res.Content {
+				files = append(files, debridFile{Name: content.Path, Size: content.Size, Link: content.Link})
+			}
+
+			file, found := pickDebridFile(files, target)
+			if !found {
+				return "", errors.New("premiumize: file not found in the torrent")
+			}
+			return file.Link, nil
+		}
+
+		if time.Now().After(deadline) {
+			return "", errors.New("premiumize: torrent not ready")
+		}
+		if !sleepContext(ctx, debridPollInterval) {
+			return "", ctx.Err()
+		}
+	}
+}
+
+// Unrestrict returns the link as is, Premiumize links are already direct.
+func (p *premiumize) Unrestrict(ctx context.Context, link string) (string, error) {
+	if len(link) == 0 {
+		return "", errors.New("premiumize: no download link")
+	}
+	return link, nil
+}
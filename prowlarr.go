@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/magnet"
+	"github.com/daniwalter001/jackett_fiber/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type prowlarr struct {
+	server types.Server
+}
+
+// Prowlarr knows indexers by numeric id, the ids of named ones are looked up
+// per host and fetched again after a while. Unknown names refresh them
+// sooner, for indexers added since.
+const prowlarrIdsTTL = time.Hour
+const prowlarrIdsRefresh = time.Minute
+
+type cachedProwlarrIds struct {
+	byName  map[string]string
+	fetched time.Time
+}
+
+var prowlarrIndexerIds = sync.Map{}
+
+func (p *prowlarr) get(ctx context.Context, path string, params url.Values, v any) error {
+	api := fmt.Sprintf("%s%s?%s", p.server.Host, path, params.Encode())
+	fmt.Println(api)
+
+	request := fiber.Get(api).Timeout(timeoutFor(ctx, 15*time.Second))
+	request.Set("X-Api-Key", p.server.ApiKey)
+
+	status, data, errs := request.Bytes()
+	if len(errs) > 0 {
+		return upstreamError(stageSearch, p.server.Host, 0, errs[0])
+	}
+	fmt.Printf("Status code: %d\n", status)
+	if status >= 400 {
+		return upstreamError(stageSearch, p.server.Host, status, nil)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return indexerError(p.server.Host, err)
+	}
+	return nil
+}
+
+// indexerId maps our indexer ids to Prowlarr ones: numbers are used as is,
+// "all" is every torrent indexer (-2) and names are matched on the indexer
+// list.
+func (p *prowlarr) indexerId(ctx context.Context, id string) (string, error) {
+	if _, err := strconv.Atoi(id); err == nil {
+		return id, nil
+	}
+	if id == "all" {
+		return "-2", nil
+	}
+
+	ids, err := p.indexerIds(ctx, prowlarrIdsTTL)
+	if err != nil {
+		return "", err
+	}
+
+	prowlarrId, ok := ids[prowlarrName(id)]
+	if !ok {
+		if ids, err = p.indexerIds(ctx, prowlarrIdsRefresh); err != nil {
+			return "", err
+		}
+		prowlarrId, ok = ids[prowlarrName(id)]
+	}
+	if !ok {
+		return "", indexerError(p.server.Host, fmt.Errorf("unknown indexer %s", id))
+	}
+	return prowlarrId, nil
+}
+
+// indexerIds returns the ids of the host by indexer name, from cache when
+// fetched less than maxAge ago.
+func (p *prowlarr) indexerIds(ctx context.Context, maxAge time.Duration) (map[string]string, error) {
+	if cached, ok := prowlarrIndexerIds.Load(p.server.Host); ok {
+		entry := cached.(cachedProwlarrIds)
+		if time.Since(entry.fetched) < maxAge {
+			return entry.byName, nil
+		}
+	}
+
+	var indexers []types.ProwlarrIndexer
+	if err := p.get(ctx, "/api/v1/indexer", url.Values{}, &indexers); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]string, len(indexers)*2)
+	for _, indexer := range indexers {
+		byName[prowlarrName(indexer.Name)] = strconv.Itoa(indexer.ID)
+		byName[prowlarrName(indexer.DefinitionName)] = strconv.Itoa(indexer.ID)
+	}
+	prowlarrIndexerIds.Store(p.server.Host, cachedProwlarrIds{byName: byName, fetched: time.Now()})
+	return byName, nil
+}
+
+func prowlarrName(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "", ".", "").Replace(name))
+}
+
+// Caps reads the capabilities on the per indexer torznab endpoint (/<id>/api)
+func (p *prowlarr) Caps(ctx context.Context, indexer types.Indexer) (types.TorznabCaps, error) {
+	var caps types.TorznabCaps
+
+	indexerId, err := p.indexerId(ctx, indexer.ID)
+	if err != nil {
+		return caps, err
+	}
+	if strings.HasPrefix(indexerId, "-") {
+		return caps, indexerError(p.server.Host, fmt.Errorf("no caps for %s", indexer.ID))
+	}
+
+	request := fiber.Get(fmt.Sprintf("%s/%s/api?t=caps&apikey=%s", p.server.Host, indexerId, p.server.ApiKey)).Timeout(timeoutFor(ctx, 10*time.Second))
+
+	status, data, errs := request.Bytes()
+	if len(errs) > 0 {
+		return caps, upstreamError(stageSearch, indexer.ID, 0, errs[0])
+	}
+	if status >= 400 {
+		return caps, upstreamError(stageSearch, indexer.ID, status, nil)
+	}
+
+	if err := xml.Unmarshal(data, &caps); err != nil {
+		return caps, indexerError(indexer.ID, err)
+	}
+	return caps, nil
+}
+
+func (p *prowlarr) Search(ctx context.Context, query types.SearchQuery, type_ string, indexer types.Indexer) ([]types.ItemsParsed, error) {
+	indexerId, err := p.indexerId(ctx, indexer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"query":      {prowlarrQuery(query)},
+		"type":       {"search"},
+		"indexerIds": {indexerId},
+	}
+	if query.Type == "tvsearch" || query.Type == "movie" {
+		params.Set("type", query.Type)
+	}
+	for _, category := range strings.Split(categoryFor(indexer, type_), ",") {
+		params.Add("categories", category)
+	}
+	if indexer.MaxResults > 0 {
+		params.Set("limit", strconv.Itoa(indexer.MaxResults))
+	}
+
+	var results []types.ProwlarrSearchResult
+	if err := p.get(ctx, "/api/v1/search", params, &results); err != nil {
+		return nil, err
+	}
+
+	return parseProwlarr(results, indexer), nil
+}
+
+// prowlarrQuery writes ID based searches with the Prowlarr search tokens,
+// e.g. {ImdbId:tt0944947}{Season:1}{Episode:2}
+func prowlarrQuery(query types.SearchQuery) string {
+	if query.Type != "tvsearch" && query.Type != "movie" {
+		return removeAccents(query.Query)
+	}
+
+	tokens := fmt.Sprintf("{ImdbId:%s}", query.ImdbID)
+	if query.Season > 0 {
+		tokens += fmt.Sprintf("{Season:%d}", query.Season)
+	}
+	if query.Episode > 0 {
+		tokens += fmt.Sprintf("{Episode:%d}", query.Episode)
+	}
+	return tokens
+}
+
+func parseProwlarr(results []types.ProwlarrSearchResult, indexer types.Indexer) []types.ItemsParsed {
+	var parsedItems []types.ItemsParsed
+	for _, result := range results {
+		if result.Protocol != "" && result.Protocol != "torrent" {
+			continue
+		}
+
+		a := types.ItemsParsed{
+			Title:     result.Title,
+			Link:      result.DownloadURL,
+			Tracker:   result.Indexer,
+			Indexer:   indexer.ID,
+			MagnetURI: result.DownloadURL,
+			InfoHash:  result.InfoHash,
+			Size:      result.Size,
+			Seeders:   strconv.Itoa(result.Seeders),
+			// Jackett peers count the seeders too
+			Peers: strconv.Itoa(result.Seeders + result.Leechers),
+		}
+		if magnet.IsMagnet(result.MagnetURL) {
+			a.MagnetURI = result.MagnetURL
+		}
+		parsedItems = append(parsedItems, withMagnet(a))
+
+		if indexer.MaxResults > 0 && len(parsedItems) >= indexer.MaxResults {
+			break
+		}
+	}
+
+	return parsedItems
+}
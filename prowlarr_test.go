@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// ageIndexerIds makes the cached ids of a host look fetched that long ago
+func ageIndexerIds(host string, age time.Duration) {
+	cached, _ := prowlarrIndexerIds.Load(host)
+	entry := cached.(cachedProwlarrIds)
+	entry.fetched = time.Now().Add(-age)
+	prowlarrIndexerIds.Store(host, entry)
+}
+
+func TestProwlarrIndexerId(t *testing.T) {
+	var mu sync.Mutex
+	var lists atomic.Int32
+	indexers := []types.ProwlarrIndexer{{ID: 3, Name: "YGGTorrent", DefinitionName: "yggtorrent"}}
+
+	host := fakeServer(t, map[string]http.HandlerFunc{
+		"GET /api/v1/indexer": func(w http.ResponseWriter, r *http.Request) {
+			lists.Add(1)
+			mu.Lock()
+			defer mu.Unlock()
+			writeJSON(w, http.StatusOK, indexers)
+		},
+	})
+	p := &prowlarr{server: types.Server{Host: host, ApiKey: "key", Backend: "prowlarr"}}
+
+	steps := []struct {
+		name  string
+		id    string
+		add   bool
+		age   time.Duration
+		want  string
+		lists int32
+	}{
+		{"numeric id", "12", false, 0, "12", 0},
+		{"all", "all", false, 0, "-2", 0},
+		{"by definition", "yggtorrent", false, 0, "3", 1},
+		{"by name", "YGG Torrent", false, 0, "3", 1},
+		{"added just after the fetch", "nyaasi", true, 0, "", 1},
+		{"added a while ago", "nyaasi", false, 2 * prowlarrIdsRefresh, "5", 2},
+		{"known", "nyaasi", false, 2 * prowlarrIdsRefresh, "5", 2},
+		{"expired", "yggtorrent", false, prowlarrIdsTTL, "3", 3},
+	}
+
+	for _, step := range steps {
+		if step.add {
+			mu.Lock()
+			indexers = append(indexers, types.ProwlarrIndexer{ID: 5, Name: "Nyaa.si", DefinitionName: "nyaasi"})
+			mu.Unlock()
+		}
+		if step.age > 0 {
+			ageIndexerIds(host, step.age)
+		}
+
+		id, err := p.indexerId(context.Background(), step.id)
+		if step.want == "" && err == nil {
+			t.Errorf("%s: id %s, want an unknown indexer", step.name, id)
+		}
+		if step.want != "" && (err != nil || id != step.want) {
+			t.Errorf("%s: id %s (%v), want %s", step.name, id, err, step.want)
+		}
+		if lists.Load() != step.lists {
+			t.Errorf("%s: %d indexer lists fetched, want %d", step.name, lists.Load(), step.lists)
+		}
+	}
+}
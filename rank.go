@@ -0,0 +1,217 @@
+package main
+
+import (
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/daniwalter001/jackett_fiber/release"
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// criteria of the stream order, the keys of the user weights
+const (
+	rankResolution = "resolution"
+	rankCached     = "cached"
+	rankSeeders    = "seeders"
+	rankSize       = "size"
+	rankLanguage   = "language"
+	rankCodec      = "codec"
+	rankIndexer    = "indexer"
+)
+
+var rankCriteria = []string{rankResolution, rankCached, rankSeeders, rankSize, rankLanguage, rankCodec, rankIndexer}
+
+var defaultWeights = map[string]float64{
+	rankResolution: 3,
+	rankCached:     4,
+	rankSeeders:    2,
+	rankSize:       1,
+	rankLanguage:   3,
+	rankCodec:      1,
+	rankIndexer:    1,
+}
+
+// trust of the indexers missing from INDEXER_TRUST
+const defaultIndexerTrust = 0.5
+
+// release tags that mean the audio is in the language, subtitled releases
+// (VOSTFR) don't count
+var audioLanguageTags = map[string][]string{
+	"french": {"FRENCH", "TRUEFRENCH", "VF", "VFF", "VFQ", "VFI", "VF2", "MULTI"},
+}
+
+// rankWeights merges the user weights over the defaults. The legacy sort
+// option boosts its criterion.
+func rankWeights(cfg types.UserConfig) map[string]float64 {
+	weights := make(map[string]float64, len(defaultWeights))
+	for criterion, weight := range defaultWeights {
+		weights[criterion] = weight
+	}
+
+	switch cfg.SortBy {
+	case "quality":
+		weights[rankResolution] *= 2
+	case "size":
+		weights[rankSize] *= 4
+	}
+
+	for criterion, weight := range cfg.Weights {
+		if _, ok := weights[criterion]; ok && weight >= 0 {
+			weights[criterion] = weight
+		}
+	}
+	return weights
+}
+
+// indexerTrust reads INDEXER_TRUST, e.g. yggtorrent:1,nyaasi:0.8
+func indexerTrust() map[string]float64 {
+	trust := make(map[string]float64)
+	for _, entry := range strings.Split(os.Getenv("INDEXER_TRUST"), ",") {
+		id, value, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			continue
+		}
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			trust[strings.ToLower(id)] = v
+		}
+	}
+	return trust
+}
+
+// resultTrust is the trust of the most trusted indexer that returned the
+// result.
+func resultTrust(item types.ItemsParsed, trust map[string]float64) float64 {
+	indexers := item.Indexers
+	if len(indexers) == 0 {
+		indexers = []string{item.Indexer}
+	}
+
+	best := -1.0
+	for _, indexer := range indexers {
+		t, ok := trust[strings.ToLower(indexer)]
+		if !ok {
+			t = defaultIndexerTrust
+		}
+		best = max(best, t)
+	}
+	return best
+}
+
+func hasAudio(name string, language string) bool {
+	tags, ok := audioLanguageTags[language]
+	if !ok {
+		tags = languageTags[language]
+	}
+
+	languages := release.Parse(name).Languages
+	return slices.ContainsFunc(tags, func(tag string) bool {
+		return slices.Contains(languages, tag)
+	})
+}
+
+// filterResults drops the results the user never wants to see.
+func filterResults(results []types.ItemsParsed, cfg types.UserConfig) []types.ItemsParsed {
+	return filter(results, func(item types.ItemsParsed) bool {
+		if slices.ContainsFunc(cfg.ExcludeKeywords, func(keyword string) bool {
+			return keyword != "" && strings.Contains(strings.ToLower(item.Title), strings.ToLower(keyword))
+		}) {
+			return false
+		}
+		if cfg.MaxQuality != "" && !withinQuality(item.Title, cfg.MaxQuality) {
+			return false
+		}
+		if cfg.ExcludeCam && release.Parse(item.Title).Cam {
+			return false
+		}
+		if cfg.MaxSizeGB > 0 && float64(item.Size) > cfg.MaxSizeGB*(1<<30) {
+			return false
+		}
+		if cfg.RequireLanguage != "" && !hasAudio(item.Title, cfg.RequireLanguage) {
+			return false
+		}
+		return true
+	})
+}
+
+// sizeFit is 1 for the preferred size and goes down as the size gets 4 times
+// bigger or smaller. Without a preference bigger is better.
+func sizeFit(size int64, biggest int64, preferredGB float64) float64 {
+	if size <= 0 {
+		return 0
+	}
+	if preferredGB <= 0 {
+		if biggest <= 0 {
+			return 0
+		}
+		return float64(size) / float64(biggest)
+	}
+
+	distance := math.Abs(math.Log(float64(size) / (preferredGB * (1 << 30))))
+	return math.Max(0, 1-distance/math.Log(4))
+}
+
+// rankResults orders the results by their weighted score, best first. Every
+// criterion scores between 0 and 1 before being weighted.
+func rankResults(results []types.ItemsParsed, cfg types.UserConfig) {
+	weights := rankWeights(cfg)
+	trust := indexerTrust()
+
+	var mostSeeders int
+	var biggest int64
+	for _, item := range results {
+		seeders, _ := strconv.Atoi(item.Seeders)
+		mostSeeders = max(mostSeeders, seeders)
+		biggest = max(biggest, item.Size)
+	}
+
+	score := func(item types.ItemsParsed) float64 {
+		info := release.Parse(item.Title)
+		seeders, _ := strconv.Atoi(item.Seeders)
+
+		criteria := map[string]float64{
+			rankResolution: float64(info.ResolutionRank()) / 4,
+			rankSize:       sizeFit(item.Size, biggest, cfg.PreferredSizeGB),
+		}
+		if item.Cached {
+			criteria[rankCached] = 1
+		}
+		if mostSeeders > 0 && seeders > 0 {
+			criteria[rankSeeders] = math.Log1p(float64(seeders)) / math.Log1p(float64(mostSeeders))
+		}
+		if len(cfg.Languages) > 0 && matchLanguages(item.Title, cfg.Languages) {
+			criteria[rankLanguage] = 1
+		}
+		if cfg.PreferredCodec != "" && strings.EqualFold(info.Codec, cfg.PreferredCodec) {
+			criteria[rankCodec] = 1
+		}
+		criteria[rankIndexer] = resultTrust(item, trust)
+
+		total := 0.0
+		for _, criterion := range rankCriteria {
+			total += weights[criterion] * criteria[criterion]
+		}
+		return total
+	}
+
+	type scored struct {
+		item  types.ItemsParsed
+		score float64
+	}
+
+	ranked := make([]scored, len(results))
+	for i, item := range results {
+		ranked[i] = scored{item: item, score: score(item)}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	for i := range ranked {
+		results[i] = ranked[i].item
+	}
+}
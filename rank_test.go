@@ -0,0 +1,97 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+func rankItem(title string, sizeGB float64, seeders string) types.ItemsParsed {
+	return types.ItemsParsed{Title: title, Size: int64(sizeGB * (1 << 30)), Seeders: seeders, Indexer: "yggtorrent"}
+}
+
+func TestRankResults(t *testing.T) {
+	t.Setenv("INDEXER_TRUST", "")
+
+	tests := []struct {
+		name    string
+		cfg     types.UserConfig
+		results []types.ItemsParsed
+		want    []string
+	}{
+		{
+			"quality first",
+			types.UserConfig{},
+			[]types.ItemsParsed{
+				rankItem("Movie.2023.720p.WEB", 2, "10"),
+				rankItem("Movie.2023.2160p.WEB", 2, "10"),
+				rankItem("Movie.2023.1080p.WEB", 2, "10"),
+			},
+			[]string{"Movie.2023.2160p.WEB", "Movie.2023.1080p.WEB", "Movie.2023.720p.WEB"},
+		},
+		{
+			"bigger file for the same quality",
+			types.UserConfig{},
+			[]types.ItemsParsed{
+				rankItem("Movie.2023.1080p.WEB-A", 2, "10"),
+				rankItem("Movie.2023.1080p.WEB-B", 8, "10"),
+				rankItem("Movie.2023.1080p.WEB-C", 4, "10"),
+			},
+			[]string{"Movie.2023.1080p.WEB-B", "Movie.2023.1080p.WEB-C", "Movie.2023.1080p.WEB-A"},
+		},
+		{
+			"closest to the preferred size",
+			types.UserConfig{PreferredSizeGB: 4},
+			[]types.ItemsParsed{
+				rankItem("Movie.2023.1080p.WEB-A", 2, "10"),
+				rankItem("Movie.2023.1080p.WEB-B", 8, "10"),
+				rankItem("Movie.2023.1080p.WEB-C", 4, "10"),
+			},
+			[]string{"Movie.2023.1080p.WEB-C", "Movie.2023.1080p.WEB-A", "Movie.2023.1080p.WEB-B"},
+		},
+		{
+			"equal scores keep their order",
+			types.UserConfig{},
+			[]types.ItemsParsed{
+				rankItem("Movie.2023.1080p.WEB-A", 2, "10"),
+				rankItem("Movie.2023.1080p.WEB-B", 2, "10"),
+			},
+			[]string{"Movie.2023.1080p.WEB-A", "Movie.2023.1080p.WEB-B"},
+		},
+		{
+			"cams dropped",
+			types.UserConfig{ExcludeCam: true},
+			[]types.ItemsParsed{
+				rankItem("Movie.2023.HDCAM.x264", 1, "100"),
+				rankItem("Movie.2023.720p.WEB.H264-TS", 1, "10"),
+				rankItem("Movie.2023.1080p.BluRay", 8, "10"),
+			},
+			[]string{"Movie.2023.1080p.BluRay", "Movie.2023.720p.WEB.H264-TS"},
+		},
+		{
+			"cams kept",
+			types.UserConfig{},
+			[]types.ItemsParsed{
+				rankItem("Movie.2023.HDCAM.x264", 1, "100"),
+				rankItem("Movie.2023.1080p.BluRay", 8, "10"),
+			},
+			[]string{"Movie.2023.1080p.BluRay", "Movie.2023.HDCAM.x264"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := filterResults(test.results, test.cfg)
+			rankResults(results, test.cfg)
+
+			var got []string
+			for _, item := range results {
+				got = append(got, item.Title)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("order %v, want %v", got, test.want)
+			}
+		})
+	}
+}
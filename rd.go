@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,36 +17,65 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-var keys = []string{"JMY424SDLO42UT46TXHBSSIFJSDYZJO3PVL5JDA7CKTNIG7YWFWA",
-	"3ZQITZP34YX3M2DKHF6TUPRKD5FUD7EPPORFV65ZZVBQBAI6BQAA",
-	"TUCIGWCX5VJCPB5YPAD64NB25TZFFGAWGDVHELHZDLNUJEGX45BA",
+const rdBaseURL = "https://api.real-debrid.com/rest/1.0"
+
+// how many of the latest torrents of the account are looked at for reuse
+const rdTorrentsLimit = 100
+
+// rdDeploymentKey is the Real-Debrid key of installs without a configuration
+// token, users with their own account set it on /configure.
+func rdDeploymentKey() string {
+	return os.Getenv("RD_API_KEY")
+}
+
+// useDebrid reports whether installs without a configuration token should
+// resolve streams through Real-Debrid with the deployment key.
+func useDebrid() bool {
+	return os.Getenv("DEBRID") == "1" && rdDeploymentKey() != ""
+}
+
+type realDebrid struct {
+	apiKey  string
+	baseURL string
 }
 
-var rdApikey = keys[rand.Intn(len(keys))]
+func newRealDebrid(apiKey string) *realDebrid {
+	return &realDebrid{apiKey: apiKey, baseURL: rdBaseURL}
+}
+
+func (r *realDebrid) bearer() string {
+	return fmt.Sprintf("Bearer %s", r.apiKey)
+}
 
-func bearer() string {
-	return fmt.Sprintf("Bearer %s", rdApikey)
+func rdErr(err rd.RdError) error {
+	if err.ErrorCode != 0 {
+		return fmt.Errorf("real-debrid: %s (%d)", err.Error, err.ErrorCode)
+	}
+	return fmt.Errorf("real-debrid: %s", err.Error)
 }
 
-func checkTorrentFileinRD(hash string) (rd.AvailabilityResponse, rd.RdError) {
+func (r *realDebrid) checkTorrentFileinRD(ctx context.Context, hash string) (rd.AvailabilityResponse, rd.RdError) {
 	if len(hash) == 0 {
 		return rd.AvailabilityResponse{}, rd.RdError{}
 
 	}
-	api := fmt.Sprintf("https://api.real-debrid.com/rest/1.0/torrents/instantAvailability/%s", hash)
+	api := fmt.Sprintf("%s/torrents/instantAvailability/%s", r.baseURL, hash)
 
-	request := fiber.Get(api).Timeout(5 * time.Second)
-	request.Set("Authorization", bearer())
+	request := fiber.Get(api).Timeout(timeoutFor(ctx, debridTimeout))
+	request.Set("Authorization", r.bearer())
 
-	status, data, err := request.Bytes()
+	status, data, errs := request.Bytes()
 
-	if err != nil {
-		return rd.AvailabilityResponse{}, rd.RdError{}
+	if len(errs) > 0 {
+		return rd.AvailabilityResponse{}, rd.RdError{Error: errs[0].Error()}
 	}
 
 	if status >= 400 {
 		var resErr rd.RdError
 		json.Unmarshal(data, &resErr)
+		if resErr.Error == "" {
+			resErr.Error = http.StatusText(status)
+		}
 		return rd.AvailabilityResponse{}, resErr
 	}
 	var resJson rd.AvailabilityResponse
@@ -51,57 +84,76 @@ func checkTorrentFileinRD(hash string) (rd.AvailabilityResponse, rd.RdError) {
 
 }
 
-func addTorrentFileinRD2(magnet string) (rd.AddTorrentResponse, rd.RdError) {
-	if len(magnet) == 0 {
-		return rd.AddTorrentResponse{}, rd.RdError{Error: "magnet not defined"}
+func (r *realDebrid) post(ctx context.Context, api string, payload url.Values) ([]byte, rd.RdError) {
+	req, errReq := http.NewRequestWithContext(ctx, "POST", api, strings.NewReader(payload.Encode()))
+	if errReq != nil {
+		return nil, rd.RdError{Error: errReq.Error()}
 	}
 
-	url := "https://api.real-debrid.com/rest/1.0/torrents/addMagnet"
-
-	payload := strings.NewReader(fmt.Sprintf("magnet=%s", magnet))
-
-	req, _ := http.NewRequest("POST", url, payload)
-
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("User-Agent", "insomnia/8.6.1")
-	req.Header.Add("Authorization", bearer())
+	req.Header.Add("Authorization", r.bearer())
 
-	res, _ := http.DefaultClient.Do(req)
+	client := http.Client{Timeout: 2 * debridTimeout}
+	res, errReq := client.Do(req)
+	if errReq != nil {
+		return nil, rd.RdError{Error: errReq.Error()}
+	}
+	defer res.Body.Close()
 
 	body, _ := io.ReadAll(res.Body)
 
 	if res.StatusCode >= 400 {
 		var resErr rd.RdError
 		json.Unmarshal(body, &resErr)
+		if resErr.Error == "" {
+			resErr.Error = res.Status
+		}
+		return nil, resErr
+	}
+
+	return body, rd.RdError{}
+}
+
+func (r *realDebrid) addTorrentFileinRD2(ctx context.Context, magnet string) (rd.AddTorrentResponse, rd.RdError) {
+	if len(magnet) == 0 {
+		return rd.AddTorrentResponse{}, rd.RdError{Error: "magnet not defined"}
+	}
+
+	body, resErr := r.post(ctx, fmt.Sprintf("%s/torrents/addMagnet", r.baseURL), url.Values{"magnet": {magnet}})
+	if resErr.Error != "" {
 		return rd.AddTorrentResponse{}, resErr
 	}
+
 	var resJson rd.AddTorrentResponse
 	json.Unmarshal(body, &resJson)
-	defer res.Body.Close()
 	return resJson, rd.RdError{}
 
 }
 
-func getTorrentInfofromRD(id string) (rd.TorrentInfoResponse, rd.RdError) {
+func (r *realDebrid) getTorrentInfofromRD(ctx context.Context, id string) (rd.TorrentInfoResponse, rd.RdError) {
 	if len(id) == 0 {
-		return rd.TorrentInfoResponse{}, rd.RdError{}
+		return rd.TorrentInfoResponse{}, rd.RdError{Error: "id not defined"}
 
 	}
 
-	api := fmt.Sprintf("https://api.real-debrid.com/rest/1.0/torrents/info/%s", id)
+	api := fmt.Sprintf("%s/torrents/info/%s", r.baseURL, id)
 
-	request := fiber.Get(api).Timeout(5 * time.Second)
-	request.Set("Authorization", bearer())
+	request := fiber.Get(api).Timeout(timeoutFor(ctx, debridTimeout))
+	request.Set("Authorization", r.bearer())
 
-	status, data, err := request.Bytes()
+	status, data, errs := request.Bytes()
 
-	if err != nil {
-		return rd.TorrentInfoResponse{}, rd.RdError{}
+	if len(errs) > 0 {
+		return rd.TorrentInfoResponse{}, rd.RdError{Error: errs[0].Error()}
 	}
 
 	if status != fiber.StatusOK {
 		var resErr rd.RdError
 		json.Unmarshal(data, &resErr)
+		if resErr.Error == "" {
+			resErr.Error = http.StatusText(status)
+		}
 		return rd.TorrentInfoResponse{}, resErr
 	}
 	var resJson rd.TorrentInfoResponse
@@ -110,7 +162,38 @@ func getTorrentInfofromRD(id string) (rd.TorrentInfoResponse, rd.RdError) {
 
 }
 
-func selectFilefromRD(id string, files string) (bool, rd.RdError) {
+// getTorrentsfromRD lists the latest torrents of the account
+func (r *realDebrid) getTorrentsfromRD(ctx context.Context) (rd.TorrentsResponse, rd.RdError) {
+	api := fmt.Sprintf("%s/torrents?limit=%d", r.baseURL, rdTorrentsLimit)
+
+	request := fiber.Get(api).Timeout(timeoutFor(ctx, debridTimeout))
+	request.Set("Authorization", r.bearer())
+
+	status, data, errs := request.Bytes()
+
+	if len(errs) > 0 {
+		return nil, rd.RdError{Error: errs[0].Error()}
+	}
+
+	// an empty account is a 204
+	if status == fiber.StatusNoContent {
+		return nil, rd.RdError{}
+	}
+	if status != fiber.StatusOK {
+		var resErr rd.RdError
+		json.Unmarshal(data, &resErr)
+		if resErr.Error == "" {
+			resErr.Error = http.StatusText(status)
+		}
+		return nil, resErr
+	}
+	var resJson rd.TorrentsResponse
+	json.Unmarshal(data, &resJson)
+	return resJson, rd.RdError{}
+
+}
+
+func (r *realDebrid) selectFilefromRD(ctx context.Context, id string, files string) (bool, rd.RdError) {
 	if len(id) == 0 {
 		return false, rd.RdError{Error: "id not defined"}
 	}
@@ -118,58 +201,177 @@ func selectFilefromRD(id string, files string) (bool, rd.RdError) {
 		files = "all"
 	}
 
-	api := fmt.Sprintf("https://api.real-debrid.com/rest/1.0/torrents/selectFiles/%s", id)
+	_, resErr := r.post(ctx, fmt.Sprintf("%s/torrents/selectFiles/%s", r.baseURL, id), url.Values{"files": {files}})
+	if resErr.Error != "" {
+		return false, resErr
+	}
+	return true, rd.RdError{}
+
+}
 
-	payload := strings.NewReader(fmt.Sprintf("files=%s", files))
+func (r *realDebrid) unrestrictLinkfromRD(ctx context.Context, link string) (rd.UnrestrictLinkResponse, rd.RdError) {
+	if len(link) == 0 {
+		return rd.UnrestrictLinkResponse{}, rd.RdError{Error: "link not defined"}
+	}
 
-	req, _ := http.NewRequest("POST", api, payload)
+	body, resErr := r.post(ctx, fmt.Sprintf("%s/unrestrict/link", r.baseURL), url.Values{"link": {link}})
+	if resErr.Error != "" {
+		return rd.UnrestrictLinkResponse{}, resErr
+	}
 
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("User-Agent", "insomnia/8.6.1")
-	req.Header.Add("Authorization", bearer())
+	var resJson rd.UnrestrictLinkResponse
+	json.Unmarshal(body, &resJson)
+	return resJson, rd.RdError{}
 
-	res, _ := http.DefaultClient.Do(req)
+}
 
-	body, _ := io.ReadAll(res.Body)
+func (r *realDebrid) CheckCached(ctx context.Context, hashes []string) (map[string]bool, error) {
+	cached := make(map[string]bool, len(hashes))
+	if len(hashes) == 0 {
+		return cached, nil
+	}
 
-	if res.StatusCode >= 400 {
-		var resErr rd.RdError
-		json.Unmarshal(body, &resErr)
-		return false, resErr
+	availability, errRd := r.checkTorrentFileinRD(ctx, strings.Join(hashes, "/"))
+	if errRd.Error != "" {
+		return cached, rdErr(errRd)
 	}
-	defer res.Body.Close()
-	return true, rd.RdError{}
 
+	for hash, hosters := range availability {
+		cached[strings.ToLower(hash)] = len(hosters["rd"]) > 0
+	}
+	return cached, nil
 }
 
-func unrestrictLinkfromRD(link string) (rd.UnrestrictLinkResponse, rd.RdError) {
-	if len(link) == 0 {
-		return rd.UnrestrictLinkResponse{}, rd.RdError{}
+// rdFailed are the states of a torrent RD will never download
+func rdFailed(status string) bool {
+	switch status {
+	case "magnet_error", "error", "virus", "dead":
+		return true
 	}
+	return false
+}
 
-	api := "https://api.real-debrid.com/rest/1.0/unrestrict/link"
+// FindTorrent reuses a torrent of the account when the wanted file is part of
+// its selection, or when no file is selected yet.
+func (r *realDebrid) FindTorrent(ctx context.Context, infoHash string, target debridTarget) (string, error) {
+	torrents, errRd := r.getTorrentsfromRD(ctx)
+	if errRd.Error != "" {
+		return "", rdErr(errRd)
+	}
 
-	payload := strings.NewReader(fmt.Sprintf("link=%s", link))
+	for _, torrent := range torrents {
+		if strings.ToLower(torrent.Hash) != infoHash || rdFailed(torrent.Status) {
+			continue
+		}
+		if torrent.Status == "waiting_files_selection" {
+			return torrent.ID, nil
+		}
+
+		info, errRd := r.getTorrentInfofromRD(ctx, torrent.ID)
+		if errRd.Error != "" {
+			return "", rdErr(errRd)
+		}
+		if _, selected := rdSelectedLink(info, target); selected {
+			return torrent.ID, nil
+		}
+	}
+	return "", nil
+}
 
-	req, _ := http.NewRequest("POST", api, payload)
+func (r *realDebrid) AddMagnet(ctx context.Context, magnet string) (string, error) {
+	added, errRd := r.addTorrentFileinRD2(ctx, magnet)
+	if errRd.Error != "" {
+		return "", rdErr(errRd)
+	}
+	if len(added.ID) == 0 {
+		return "", errors.New("real-debrid: torrent not added")
+	}
+	return added.ID, nil
+}
 
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("User-Agent", "insomnia/8.6.1")
-	req.Header.Add("Authorization", bearer())
+// rdFileID is the RD id of a file, RD counts them from 1
+func rdFileID(fileIdx int) string {
+	return strconv.Itoa(fileIdx + 1)
+}
 
-	res, _ := http.DefaultClient.Do(req)
+// SelectFiles only selects once RD is done converting the magnet, WaitReady
+// takes care of it otherwise.
+func (r *realDebrid) SelectFiles(ctx context.Context, id string, target debridTarget) error {
+	info, errRd := r.getTorrentInfofromRD(ctx, id)
+	if errRd.Error != "" {
+		return rdErr(errRd)
+	}
+	if info.Status != "waiting_files_selection" {
+		return nil
+	}
 
-	body, _ := io.ReadAll(res.Body)
+	if _, errRd := r.selectFilefromRD(ctx, id, rdFileID(target.Index)); errRd.Error != "" {
+		return rdErr(errRd)
+	}
+	return nil
+}
 
-	if res.StatusCode >= 400 {
-		var resErr rd.RdError
-		json.Unmarshal(body, &resErr)
-		return rd.UnrestrictLinkResponse{}, resErr
+func (r *realDebrid) WaitReady(ctx context.Context, id string, target debridTarget) (string, error) {
+	deadline := time.Now().Add(debridReadyTimeout)
+
+	for {
+		info, errRd := r.getTorrentInfofromRD(ctx, id)
+		if errRd.Error != "" {
+			return "", rdErr(errRd)
+		}
+
+		if info.Status == "waiting_files_selection" {
+			if _, errRd := r.selectFilefromRD(ctx, id, rdFileID(target.Index)); errRd.Error != "" {
+				return "", rdErr(errRd)
+			}
+		}
+		if rdFailed(info.Status) {
+			return "", fmt.Errorf("real-debrid: torrent %s", info.Status)
+		}
+
+		if info.Status == "downloaded" {
+			if link, _ := rdSelectedLink(info, target); link != "" {
+				return link, nil
+			}
+			return "", errors.New("real-debrid: file not found in the torrent")
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("real-debrid: torrent not ready (%s)", info.Status)
+		}
+		if !sleepContext(ctx, debridPollInterval) {
+			return "", ctx.Err()
+		}
 	}
+}
 
-	var resJson rd.UnrestrictLinkResponse
-	json.Unmarshal(body, &resJson)
-	defer res.Body.Close()
-	return resJson, rd.RdError{}
+// rdSelectedLink finds the link of the target file, RD has one link per
+// selected file in the order of the files. selected is false when the file is
+// not part of the selection, the link is empty until it is downloaded.
+func rdSelectedLink(info rd.TorrentInfoResponse, target debridTarget) (link string, selected bool) {
+	position := 0
+	for _, file := range info.Files {
+		if file.Selected != 1 {
+			continue
+		}
+		if strconv.Itoa(file.ID) == rdFileID(target.Index) {
+			if position < len(info.Links) {
+				return info.Links[position], true
+			}
+			return "", true
+		}
+		position++
+	}
+	return "", false
+}
 
+func (r *realDebrid) Unrestrict(ctx context.Context, link string) (string, error) {
+	unrestricted, errRd := r.unrestrictLinkfromRD(ctx, link)
+	if errRd.Error != "" {
+		return "", rdErr(errRd)
+	}
+	if len(unrestricted.Download) == 0 {
+		return "", errors.New("real-debrid: no download link")
+	}
+	return unrestricted.Download, nil
 }
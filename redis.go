@@ -3,22 +3,24 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
 )
 
-func RedisClient() redis.Client {
-
-	errDot := godotenv.Load("./.env")
-	if errDot != nil {
-		fmt.Println("Error loading .env file")
-		panic(errDot)
+// RedisClient connects to REDIS_URL. A full redis:// or rediss:// url is used
+// as is, a bare host gets the TLS url of hosted redis with REDIS_PASSWORD and
+// REDIS_PORT.
+func RedisClient() (*redis.Client, error) {
+	url := os.Getenv("REDIS_URL")
+	if !strings.HasPrefix(url, "redis://") && !strings.HasPrefix(url, "rediss://") {
+		url = fmt.Sprintf("rediss://default:%s@%s:%s/0?max_retries=2", os.Getenv("REDIS_PASSWORD"), url, os.Getenv("REDIS_PORT"))
 	}
 
-	opt, _ := redis.ParseURL(fmt.Sprintf("rediss://default:%s@%s:%s/0?max_retries=2", os.Getenv("REDIS_PASSWORD"), os.Getenv("REDIS_URL"), os.Getenv("REDIS_PORT")))
-
-	rdb := redis.NewClient(opt)
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
 
-	return *rdb
+	return redis.NewClient(opt), nil
 }
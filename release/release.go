@@ -0,0 +1,459 @@
+// Package release reads what it can out of torrent release names, e.g.
+// "Show.S01E02.MULTI.1080p.WEB-DL.x264-GRP".
+package release
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Info is what a release name tells about its content. Fields are left
+// empty when the name says nothing about them.
+type Info struct {
+	Title    string
+	Year     int
+	Seasons  []int
+	Episodes []int
+	// Absolute is set when episodes are numbered across seasons (anime)
+	Absolute bool
+
+	Resolution string   // 2160p, 1080p, 720p, 480p...
+	Source     string   // REMUX, BluRay, WEB-DL, WEBRip, HDTV, CAM...
+	Codec      string   // x264, x265, AV1...
+	BitDepth   string   // 10bit
+	HDR        []string // HDR, HDR10, HDR10+, DV, HLG
+	Audio      []string // DDP, TrueHD, Atmos, DTS-HD MA...
+	Channels   string   // 5.1, 7.1, 2.0
+	Languages  []string // MULTI, TRUEFRENCH, VOSTFR, ENGLISH...
+	Group      string
+
+	Complete bool
+	Proper   bool
+	Repack   bool
+	// Cam is set for the theater recordings: CAM, TS, TC and screeners
+	Cam    bool
+	Sample bool
+}
+
+// HasSeason reports whether the release contains the given season.
+func (i Info) HasSeason(season int) bool {
+	return slices.Contains(i.Seasons, season)
+}
+
+// HasEpisode reports whether the release contains the given episode.
+func (i Info) HasEpisode(episode int) bool {
+	return slices.Contains(i.Episodes, episode)
+}
+
+// ResolutionRank makes resolutions comparable, higher is better and 0 means
+// unknown.
+func (i Info) ResolutionRank() int {
+	return resolutionRanks[i.Resolution]
+}
+
+// parser holds a name while it is being read. Parts of work are blanked out
+// once recognized so that later patterns don't read them again.
+type parser struct {
+	name       string
+	work       []byte
+	titleStart int
+	titleEnd   int
+	sources    []string
+	info       Info
+}
+
+// Parse reads a release or file name. Paths are accepted too, folder names
+// then count as part of the name.
+func Parse(name string) Info {
+	name = videoExtension.ReplaceAllString(strings.TrimSpace(name), "")
+
+	p := &parser{name: name, work: lower(name), titleEnd: len(name)}
+
+	p.leading()
+	p.blank(crcHash)
+	p.tags()
+	p.episodes()
+	p.year()
+	p.absolute()
+	p.words()
+	p.group()
+
+	p.info.Title = cleanTitle(p.name[p.titleStart:p.titleEnd])
+	p.info.Absolute = len(p.info.Episodes) > 0 && len(p.info.Seasons) == 0
+	for _, source := range sourceOrder {
+		if slices.Contains(p.sources, source) {
+			p.info.Source = source
+			break
+		}
+	}
+	// a WEB or BluRay release naming a cam source is not a cam
+	p.info.Cam = slices.Contains(camSources, p.info.Source)
+
+	return p.info
+}
+
+// lower works on ASCII only so that indexes stay valid in the original name
+func lower(name string) []byte {
+	work := []byte(name)
+	for i, c := range work {
+		if 'A' <= c && c <= 'Z' {
+			work[i] = c + 'a' - 'A'
+		} else if c == '_' {
+			work[i] = ' '
+		}
+	}
+	return work
+}
+
+// find returns the submatch indexes of re and blanks the matches out. Strong
+// matches mark the end of the title.
+func (p *parser) find(re *regexp.Regexp, strong bool) [][]int {
+	matches := re.FindAllSubmatchIndex(p.work, -1)
+	for _, m := range matches {
+		for i := m[0]; i < m[1]; i++ {
+			p.work[i] = ' '
+		}
+		if strong && m[0] < p.titleEnd && m[0] >= p.titleStart {
+			p.titleEnd = m[0]
+		}
+	}
+	return matches
+}
+
+func (p *parser) blank(re *regexp.Regexp) {
+	p.find(re, false)
+}
+
+// text is the lowercased content of a submatch
+func (p *parser) text(m []int, group int) string {
+	if 2*group >= len(m) || m[2*group] < 0 {
+		return ""
+	}
+	return strings.ToLower(p.name[m[2*group]:m[2*group+1]])
+}
+
+func (p *parser) number(m []int, group int) int {
+	n, err := strconv.Atoi(p.text(m, group))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func (p *parser) leading() {
+	if m := leadingSite.FindIndex(p.work); m != nil {
+		p.titleStart = m[1]
+	}
+	// anime releases start with the group, [SubsPlease] Show - 01
+	if m := leadingGroup.FindSubmatchIndex(p.work[p.titleStart:]); m != nil {
+		p.info.Group = strings.TrimSpace(p.name[p.titleStart+m[2] : p.titleStart+m[3]])
+		p.titleStart += m[1]
+	}
+	for i := 0; i < p.titleStart; i++ {
+		p.work[i] = ' '
+	}
+}
+
+func (p *parser) tags() {
+	for _, m := range p.find(resolutionTag, true) {
+		p.info.Resolution = p.text(m, 1) + "p"
+	}
+	for _, m := range p.find(dimensionTag, true) {
+		p.info.Resolution = p.text(m, 1) + "p"
+	}
+	for _, m := range p.find(resolutionWord, true) {
+		if p.info.Resolution == "" {
+			p.info.Resolution = resolutions[p.text(m, 1)]
+		}
+	}
+
+	for _, m := range p.find(sourceTag, true) {
+		p.sources = append(p.sources, sources[compact(p.text(m, 1))])
+	}
+
+	for _, m := range p.find(codecTag, true) {
+		if digit := p.text(m, 1); digit != "" {
+			p.info.Codec = codecs[digit]
+		} else {
+			p.info.Codec = codecs[p.text(m, 0)]
+		}
+	}
+
+	for _, m := range p.find(bitTag, true) {
+		p.info.BitDepth = p.text(m, 1) + "bit"
+	}
+
+	for _, m := range p.find(hdrTag, true) {
+		p.info.HDR = appendTag(p.info.HDR, hdrs[compact(p.text(m, 1))])
+	}
+
+	for _, m := range p.find(audioTag, true) {
+		p.info.Audio = appendTag(p.info.Audio, audios[compact(p.text(m, 1))])
+		if m[4] >= 0 {
+			p.info.Channels = channelsOf(p.text(m, 2))
+		}
+	}
+	for _, m := range p.find(channels, false) {
+		if p.info.Channels == "" && m[0] > p.titleStart {
+			p.info.Channels = p.text(m, 1) + "." + p.text(m, 2)
+		}
+	}
+}
+
+// episodes reads seasons and episodes, from the most to the least explicit
+// way of writing them.
+func (p *parser) episodes() {
+	for _, re := range []*regexp.Regexp{seasonEpisode, crossEpisode} {
+		for _, m := range p.find(re, true) {
+			p.addSeason(p.number(m, 1))
+			p.addEpisodes(p.number(m, 2), p.number(m, 3))
+		}
+	}
+	for _, m := range p.find(specialEpisode, true) {
+		p.addSeason(0)
+		p.addEpisodes(p.number(m, 1), -1)
+	}
+	if len(p.info.Episodes) > 0 {
+		return
+	}
+
+	for _, m := range p.find(seasonRange, true) {
+		p.addSeasons(p.number(m, 1), p.number(m, 2))
+	}
+	for _, m := range p.find(wordRange, true) {
+		from := p.number(m, 1)
+		if from < 0 {
+			from = p.number(m, 2)
+		}
+		p.addSeasons(from, p.number(m, 3))
+	}
+	for _, re := range []*regexp.Regexp{seasonWord, seasonOnly} {
+		for _, m := range p.find(re, true) {
+			p.addSeason(p.number(m, 1))
+			p.addEpisodes(p.number(m, 2), -1)
+		}
+	}
+	for _, m := range p.find(episodeOnly, true) {
+		p.addEpisodes(p.number(m, 1), p.number(m, 2))
+	}
+}
+
+// year picks the last year before the title end, so that a year in the title
+// itself (2001 A Space Odyssey 1968) is kept in the title.
+func (p *parser) year() {
+	var chosen []int
+	for _, m := range year.FindAllSubmatchIndex(p.work, -1) {
+		if m[0] <= p.titleStart {
+			continue
+		}
+		if m[0] < p.titleEnd || chosen == nil {
+			chosen = m
+		}
+		if m[0] >= p.titleEnd {
+			break
+		}
+	}
+	if chosen == nil {
+		return
+	}
+
+	p.info.Year = p.number(chosen, 1)
+	for i := chosen[0]; i < chosen[1]; i++ {
+		p.work[i] = ' '
+	}
+	if chosen[0] < p.titleEnd {
+		p.titleEnd = chosen[0]
+	}
+}
+
+// absolute reads the episode numbers anime releases put right after the
+// title, with or without a dash.
+func (p *parser) absolute() {
+	if len(p.info.Episodes) > 0 {
+		return
+	}
+
+	for _, m := range p.find(dashEpisode, true) {
+		p.addEpisodes(p.number(m, 1), p.number(m, 2))
+	}
+	if len(p.info.Episodes) == 0 && len(p.info.Seasons) == 0 && p.info.Year == 0 {
+		p.trimTitle()
+		title := p.work[p.titleStart:p.titleEnd]
+		if m := bareNumber.FindSubmatchIndex(title); m != nil && m[0] > 0 {
+			p.titleEnd = p.titleStart + m[0]
+			m = shift(m, p.titleStart)
+			p.addEpisodes(p.number(m, 1), p.number(m, 2))
+		}
+	}
+}
+
+// words reads the single word tags found after the title.
+func (p *parser) words() {
+	p.trimTitle()
+
+	// source words may be group names too, Movie.1080p.WEB.H264-TS
+	groupStart := -1
+	if m := trailingTag.FindSubmatchIndex(p.work); m != nil {
+		groupStart = m[2]
+	}
+
+	for _, m := range words.FindAllIndex(p.work, -1) {
+		word := string(p.work[m[0]:m[1]])
+		if word == "sample" {
+			p.info.Sample = true
+		}
+		if m[0] < p.titleEnd {
+			continue
+		}
+
+		t, ok := wordTags[word]
+		if !ok || t.kind == kindSource && m[0] == groupStart {
+			continue
+		}
+		for i := m[0]; i < m[1]; i++ {
+			p.work[i] = ' '
+		}
+
+		switch t.kind {
+		case kindLanguage:
+			p.info.Languages = appendNew(p.info.Languages, t.value)
+		case kindSource:
+			p.sources = append(p.sources, t.value)
+		case kindResolution:
+			if p.info.Resolution == "" {
+				p.info.Resolution = t.value
+			}
+		case kindHDR:
+			p.info.HDR = appendNew(p.info.HDR, t.value)
+		case kindFlag:
+			switch t.value {
+			case "complete":
+				p.info.Complete = true
+			case "proper":
+				p.info.Proper = true
+			case "repack":
+				p.info.Repack = true
+			}
+		}
+	}
+}
+
+// trimTitle gives back to the tags the words at the end of the title that
+// are known tags, e.g. FRENCH in Movie.FRENCH.1080p
+func (p *parser) trimTitle() {
+	for {
+		title := p.work[p.titleStart:p.titleEnd]
+		found := words.FindAllIndex(title, -1)
+		if len(found) < 2 {
+			return
+		}
+
+		last := found[len(found)-1]
+		if _, ok := wordTags[string(title[last[0]:last[1]])]; !ok {
+			return
+		}
+		p.titleEnd = p.titleStart + last[0]
+	}
+}
+
+// group reads the release group, Movie.1080p.x264-GROUP. The name is used
+// rather than work as tag patterns may have taken the dash.
+func (p *parser) group() {
+	if p.info.Group != "" {
+		return
+	}
+
+	m := trailingTag.FindSubmatchIndex(lower(p.name))
+	if m == nil || m[0] < p.titleEnd {
+		return
+	}
+	for i := 2; i < len(m); i += 2 {
+		if m[i] < 0 || strings.TrimSpace(string(p.work[m[i]:m[i+1]])) == "" {
+			continue
+		}
+		group := strings.TrimSpace(p.name[m[i]:m[i+1]])
+		if strings.ContainsFunc(group, func(r rune) bool { return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' }) {
+			p.info.Group = group
+		}
+	}
+}
+
+func (p *parser) addSeason(season int) {
+	if season >= 0 {
+		p.info.Seasons = appendNew(p.info.Seasons, season)
+	}
+}
+
+func (p *parser) addSeasons(from int, to int) {
+	if from < 0 || to < from || to-from > 50 {
+		p.addSeason(from)
+		return
+	}
+	for season := from; season <= to; season++ {
+		p.addSeason(season)
+	}
+}
+
+const maxEpisodeRange = 1200
+
+func (p *parser) addEpisodes(from int, to int) {
+	if from < 0 {
+		return
+	}
+	// absolute batches go up to a thousand episodes, One Piece 001-1000
+	if to < from || to-from > maxEpisodeRange {
+		to = from
+	}
+	for episode := from; episode <= to; episode++ {
+		p.info.Episodes = appendNew(p.info.Episodes, episode)
+	}
+}
+
+func appendNew[T comparable](list []T, value T) []T {
+	if slices.Contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
+
+// appendTag skips the tags missing from the tables
+func appendTag(list []string, value string) []string {
+	if value == "" {
+		return list
+	}
+	return appendNew(list, value)
+}
+
+func shift(m []int, offset int) []int {
+	shifted := make([]int, len(m))
+	for i, v := range m {
+		shifted[i] = v
+		if v >= 0 {
+			shifted[i] = v + offset
+		}
+	}
+	return shifted
+}
+
+func compact(s string) string {
+	return strings.NewReplacer(" ", "", ".", "", "-", "").Replace(s)
+}
+
+func channelsOf(s string) string {
+	return compact(s)[:1] + "." + compact(s)[1:]
+}
+
+var titleSeparators = strings.NewReplacer(".", " ", "_", " ")
+
+// cleanTitle keeps the last folder of a path that still holds some title,
+// Show/Season 1/Show.S01E01 gives Show.
+func cleanTitle(title string) string {
+	if i := strings.LastIndex(title, "/"); i >= 0 && strings.TrimSpace(title[i+1:]) != "" {
+		title = title[i+1:]
+	}
+	title = titleSeparators.Replace(strings.ReplaceAll(title, "/", " "))
+	title = strings.Join(strings.Fields(title), " ")
+	return strings.Trim(title, " -([{/")
+}
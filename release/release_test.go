@@ -0,0 +1,91 @@
+package release
+
+import (
+	"slices"
+	"testing"
+)
+
+// seq is the episodes from..to, for the batches
+func seq(from int, to int) []int {
+	var list []int
+	for i := from; i <= to; i++ {
+		list = append(list, i)
+	}
+	return list
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Info
+	}{
+		// seasons and episodes
+		{"Game.of.Thrones.S01E02.MULTI.1080p.WEB-DL.x264-GRP", Info{Title: "Game of Thrones", Seasons: []int{1}, Episodes: []int{2}, Resolution: "1080p", Source: "WEB-DL", Codec: "x264", Languages: []string{"MULTI"}, Group: "GRP"}},
+		{"The.Office.S02E01E02.720p.HDTV.x264-LOL", Info{Title: "The Office", Seasons: []int{2}, Episodes: []int{1, 2}, Resolution: "720p", Source: "HDTV", Codec: "x264", Group: "LOL"}},
+		{"Show.1x05.HDTV.XviD", Info{Title: "Show", Seasons: []int{1}, Episodes: []int{5}, Source: "HDTV", Codec: "XviD"}},
+		{"Show S02 - 05 VOSTFR", Info{Title: "Show", Seasons: []int{2}, Episodes: []int{5}, Languages: []string{"VOSTFR"}}},
+		{"Show Season 2 Episode 5", Info{Title: "Show", Seasons: []int{2}, Episodes: []int{5}}},
+		{"Show/Season 1/Show.S01E01.mkv", Info{Title: "Show", Seasons: []int{1}, Episodes: []int{1}}},
+		{"Les.Simpson.S35E04.FRENCH.720p.HDTV.x264-ZT", Info{Title: "Les Simpson", Seasons: []int{35}, Episodes: []int{4}, Resolution: "720p", Source: "HDTV", Codec: "x264", Languages: []string{"FRENCH"}, Group: "ZT"}},
+		{"Show.S05E10.ENGLISH.1080p.AMZN.WEB-DL.DDP5.1.H.264-NTb", Info{Title: "Show", Seasons: []int{5}, Episodes: []int{10}, Resolution: "1080p", Source: "WEB-DL", Codec: "x264", Audio: []string{"DDP"}, Channels: "5.1", Languages: []string{"ENGLISH"}, Group: "NTb"}},
+		{"Show SP01 1080p.mkv", Info{Title: "Show", Seasons: []int{0}, Episodes: []int{1}, Resolution: "1080p"}},
+		{"Show - Special 02.mkv", Info{Title: "Show", Seasons: []int{0}, Episodes: []int{2}}},
+
+		// season packs and ranges
+		{"Show.S01.COMPLETE.MULTI.10bit.x265", Info{Title: "Show", Seasons: []int{1}, Codec: "x265", BitDepth: "10bit", Languages: []string{"MULTI"}, Complete: true}},
+		{"Breaking Bad S01-S05 COMPLETE 1080p BluRay x265", Info{Title: "Breaking Bad", Seasons: seq(1, 5), Resolution: "1080p", Source: "BluRay", Codec: "x265", Complete: true}},
+		{"Friends Saison 1 à 10 FRENCH DVDRIP", Info{Title: "Friends", Seasons: seq(1, 10), Source: "DVD", Languages: []string{"FRENCH"}}},
+		{"The Expanse S01-03 MULTi 1080p", Info{Title: "The Expanse", Seasons: seq(1, 3), Resolution: "1080p", Languages: []string{"MULTI"}}},
+		{"Shingeki no Kyojin Saison 4 VOSTFR 1080p", Info{Title: "Shingeki no Kyojin", Seasons: []int{4}, Resolution: "1080p", Languages: []string{"VOSTFR"}}},
+
+		// absolute numbering
+		{"[SubsPlease] Jujutsu Kaisen - 47 (1080p) [ABCDEF12].mkv", Info{Title: "Jujutsu Kaisen", Episodes: []int{47}, Absolute: true, Resolution: "1080p", Group: "SubsPlease"}},
+		{"[HorribleSubs] Boku no Hero Academia - 88 [720p].mkv", Info{Title: "Boku no Hero Academia", Episodes: []int{88}, Absolute: true, Resolution: "720p", Group: "HorribleSubs"}},
+		{"One Piece 1071 VOSTFR 1080p", Info{Title: "One Piece", Episodes: []int{1071}, Absolute: true, Resolution: "1080p", Languages: []string{"VOSTFR"}}},
+		{"One Piece 001-100 VOSTFR", Info{Title: "One Piece", Episodes: seq(1, 100), Absolute: true, Languages: []string{"VOSTFR"}}},
+		{"Naruto Shippuden 001-500 Integrale", Info{Title: "Naruto Shippuden", Episodes: seq(1, 500), Absolute: true, Complete: true}},
+		{"[Erai-raws] Show - 01 ~ 12", Info{Title: "Show", Episodes: seq(1, 12), Absolute: true, Group: "Erai-raws"}},
+		{"[Group] Show - 01-12 (BD 1080p)", Info{Title: "Show", Episodes: seq(1, 12), Absolute: true, Resolution: "1080p", Source: "BluRay", Group: "Group"}},
+
+		// movies, sources, HDR and audio
+		{"Movie.2019.2160p.UHD.BluRay.REMUX.HDR10.DV.TrueHD.Atmos.7.1-GRP", Info{Title: "Movie", Year: 2019, Resolution: "2160p", Source: "REMUX", HDR: []string{"HDR10", "DV"}, Audio: []string{"TrueHD", "Atmos"}, Channels: "7.1", Group: "GRP"}},
+		{"Dark.S03.FRENCH.1080p.NF.WEB-DL.DDP5.1.HDR.x265-GRP", Info{Title: "Dark", Seasons: []int{3}, Resolution: "1080p", Source: "WEB-DL", Codec: "x265", HDR: []string{"HDR"}, Audio: []string{"DDP"}, Channels: "5.1", Languages: []string{"FRENCH"}, Group: "GRP"}},
+		{"Movie.2020.VOSTFR.720p.WEBRip.AAC2.0", Info{Title: "Movie", Year: 2020, Resolution: "720p", Source: "WEBRip", Audio: []string{"AAC"}, Channels: "2.0", Languages: []string{"VOSTFR"}}},
+		{"Dune.Part.Two.2024.MULTI.VFF.2160p.WEB-DL.DV.HDR10+.DDP5.1.Atmos-FW", Info{Title: "Dune Part Two", Year: 2024, Resolution: "2160p", Source: "WEB-DL", HDR: []string{"HDR10+", "DV"}, Audio: []string{"DDP", "Atmos"}, Channels: "5.1", Languages: []string{"MULTI", "VFF"}, Group: "FW"}},
+		{"Oppenheimer.2023.TRUEFRENCH.1080p.BluRay.x264.AC3-NoTag", Info{Title: "Oppenheimer", Year: 2023, Resolution: "1080p", Source: "BluRay", Codec: "x264", Audio: []string{"DD"}, Languages: []string{"TRUEFRENCH"}, Group: "NoTag"}},
+		{"2001 A Space Odyssey 1968 1080p BluRay", Info{Title: "2001 A Space Odyssey", Year: 1968, Resolution: "1080p", Source: "BluRay"}},
+
+		// theater recordings
+		{"Movie 2023 CAM x264", Info{Title: "Movie", Year: 2023, Source: "CAM", Codec: "x264", Cam: true}},
+		{"Movie.2022.HDCAM.x264-NOGROUP", Info{Title: "Movie", Year: 2022, Source: "CAM", Codec: "x264", Group: "NOGROUP", Cam: true}},
+		{"Movie.2022.TELESYNC.x264", Info{Title: "Movie", Year: 2022, Source: "TS", Codec: "x264", Cam: true}},
+		{"Movie.2022.DVDSCR.XviD", Info{Title: "Movie", Year: 2022, Source: "SCR", Codec: "XviD", Cam: true}},
+		{"Movie.2023.HDTS.TRUEFRENCH.x264", Info{Title: "Movie", Year: 2023, Source: "TS", Codec: "x264", Languages: []string{"TRUEFRENCH"}, Cam: true}},
+		{"Movie.2023.1080p.WEB.H264-TS", Info{Title: "Movie", Year: 2023, Resolution: "1080p", Source: "WEB", Codec: "x264", Group: "TS"}},
+		{"Movie.2023.1080p.BluRay.TC.x264-GRP", Info{Title: "Movie", Year: 2023, Resolution: "1080p", Source: "BluRay", Codec: "x264", Group: "GRP"}},
+		{"Movie.2023.TS-CAM", Info{Title: "Movie", Year: 2023, Source: "TS", Group: "CAM", Cam: true}},
+
+		// flags
+		{"Show.S01E01.PROPER.REPACK.1080p", Info{Title: "Show", Seasons: []int{1}, Episodes: []int{1}, Resolution: "1080p", Proper: true, Repack: true}},
+		{"Show.S01E01.sample.mkv", Info{Title: "Show", Seasons: []int{1}, Episodes: []int{1}, Sample: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Parse(test.name)
+			if !sameInfo(got, test.want) {
+				t.Errorf("Parse(%q)\n got %+v\nwant %+v", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+// sameInfo compares two infos, nil and empty lists being the same
+func sameInfo(a Info, b Info) bool {
+	return a.Title == b.Title && a.Year == b.Year &&
+		slices.Equal(a.Seasons, b.Seasons) && slices.Equal(a.Episodes, b.Episodes) && a.Absolute == b.Absolute &&
+		a.Resolution == b.Resolution && a.Source == b.Source && a.Codec == b.Codec && a.BitDepth == b.BitDepth &&
+		slices.Equal(a.HDR, b.HDR) && slices.Equal(a.Audio, b.Audio) && a.Channels == b.Channels &&
+		slices.Equal(a.Languages, b.Languages) && a.Group == b.Group &&
+		a.Complete == b.Complete && a.Proper == b.Proper && a.Repack == b.Repack && a.Cam == b.Cam && a.Sample == b.Sample
+}
@@ -0,0 +1,208 @@
+package release
+
+import "regexp"
+
+// names are lowercased and "_" turned into spaces before matching, so the
+// patterns only deal with lowercase, dots, dashes and spaces
+
+var videoExtension = regexp.MustCompile(`(?i)\.(mkv|mp4|avi|ts|m2ts|wmv|flv|mov|webm|m4v|mpe?g)$`)
+
+var (
+	leadingGroup = regexp.MustCompile(`^\s*\[([^\]]+)\]\s*`)
+	leadingSite  = regexp.MustCompile(`^\s*\[?\s*www\.[a-z0-9-]+\.[a-z]{2,4}\s*\]?\s*-?\s*`)
+	crcHash      = regexp.MustCompile(`[\[(][0-9a-f]{8}[\])]`)
+	trailingTag  = regexp.MustCompile(`(?:-([a-z0-9]+)|\[([a-z0-9 .-]+)\])\s*$`)
+)
+
+var (
+	// S01E01, S01E01E02, S01E01-E03, S01E01-03, S01 - E01
+	seasonEpisode = regexp.MustCompile(`\bs(\d{1,2})(?:[ .]?-[ .]?|[ .])?e(\d{1,4})(?:(?:-e?|e|[ .]e)(\d{1,4}))?\b`)
+	// 1x05, s1x05
+	crossEpisode = regexp.MustCompile(`\bs?(\d{1,2})x(\d{2,3})\b`)
+	// S01-S03, S1-3, seasons 1 to 3, saison 1 à 3
+	seasonRange = regexp.MustCompile(`\bs(\d{1,2})(?:[ .]?-[ .]?s|-)(\d{1,2})\b`)
+	wordRange   = regexp.MustCompile(`\b(?:(?:seasons|saisons)[ .]?(\d{1,2})[ .]?(?:-|à|a|to|&|et)|(?:season|saison)[ .]?(\d{1,2})[ .]?(?:à|a|to|&|et))[ .]?(?:seasons?[ .]?|saisons?[ .]?)?(\d{1,2})\b`)
+	// season 2, saison 2 episode 5, season 2 - 05
+	seasonWord = regexp.MustCompile(`\b(?:season|saison|series|temporada)[ .]?(\d{1,2})\b(?:(?:[ .]?-[ .]?|[ .]?(?:e|ep|episode)[ .]?)(\d{1,4})\b)?`)
+	// S02, S2 - 05
+	seasonOnly = regexp.MustCompile(`\bs(\d{1,2})\b(?:[ .]?-[ .]?(\d{1,4})\b)?`)
+	// SP01, Special 01, stremio puts the specials in season 0
+	specialEpisode = regexp.MustCompile(`\b(?:sp|specials?)[ .]?(\d{1,3})\b`)
+	// E05, Ep 5, Episode 5-6
+	episodeOnly = regexp.MustCompile(`(?:\b(?:e|ep|episode)|épisode)[ .]?(\d{1,4})(?:[ .]?-[ .]?(?:e|ep)?[ .]?(\d{1,4}))?\b`)
+	// [Group] Show - 1043 (1080p), the usual anime numbering, and the batches
+	// Show - 01 ~ 12, Show 001-100
+	dashEpisode = regexp.MustCompile(`\s-\s(\d{1,4})(?:v\d)?(?:\s?[-~]\s?(\d{1,4})(?:v\d)?)?\b`)
+	bareNumber  = regexp.MustCompile(`\b(\d{2,4})(?:v\d)?(?:\s?[-~]\s?(\d{2,4})(?:v\d)?)?\s*$`)
+
+	year = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+)
+
+var (
+	resolutionTag  = regexp.MustCompile(`\b(2160|1440|1080|720|576|540|480|360)[pi]\b`)
+	dimensionTag   = regexp.MustCompile(`\b(?:3840|4096|1920|1440|1280|1024|960|720|640)x(2160|1080|720|576|480)\b`)
+	resolutionWord = regexp.MustCompile(`\b(4k|uhd|fhd)\b`)
+
+	sourceTag = regexp.MustCompile(`\b(remux|bdremux|blu-?ray|bdrip|brrip|web[ .-]?dl|web[ .-]?rip|hdtv|pdtv|tvrip|dvdrip|dvd-?r|hdrip|hdlight|mhd|hdcam|camrip|telesync|hdts|telecine|hdtc|dvdscr|screener)\b`)
+	codecTag  = regexp.MustCompile(`\b(?:[xh][ .]?26([45])|hevc|avc|av1|xvid|divx|vp9)\b`)
+	bitTag    = regexp.MustCompile(`\b(8|10|12)[ .-]?bits?\b`)
+	hdrTag    = regexp.MustCompile(`\b(hdr10(?:\+|plus)|hdr10|hdr|dolby[ .]?vision|dovi|hlg)(?:[^a-z0-9]|$)`)
+	audioTag  = regexp.MustCompile(`\b(ddp|dd\+|e-?ac-?3|dd|ac-?3|aac|flac|opus|mp3|truehd|atmos|dts-?hd(?:[ .]ma)?|dts-?x|dts)(?:[ .]?([257][ .][01]))?(?:[^a-z]|$)`)
+	channels  = regexp.MustCompile(`\b([257])[ .]([01])\b`)
+
+	words = regexp.MustCompile(`[a-z0-9+]+`)
+)
+
+// the tables below are keyed on matches with their separators removed
+
+var resolutions = map[string]string{
+	"4k":  "2160p",
+	"uhd": "2160p",
+	"fhd": "1080p",
+}
+
+var resolutionRanks = map[string]int{
+	"2160p": 4,
+	"1440p": 3,
+	"1080p": 3,
+	"720p":  2,
+	"576p":  1,
+	"540p":  1,
+	"480p":  1,
+	"360p":  1,
+}
+
+var sources = map[string]string{
+	"remux":    "REMUX",
+	"bdremux":  "REMUX",
+	"bluray":   "BluRay",
+	"bdrip":    "BluRay",
+	"brrip":    "BluRay",
+	"webdl":    "WEB-DL",
+	"webrip":   "WEBRip",
+	"hdtv":     "HDTV",
+	"pdtv":     "HDTV",
+	"tvrip":    "HDTV",
+	"dvdrip":   "DVD",
+	"dvdr":     "DVD",
+	"hdrip":    "HDRip",
+	"hdlight":  "HDLight",
+	"mhd":      "HDLight",
+	"hdcam":    "CAM",
+	"camrip":   "CAM",
+	"telesync": "TS",
+	"hdts":     "TS",
+	"telecine": "TC",
+	"hdtc":     "TC",
+	"dvdscr":   "SCR",
+	"screener": "SCR",
+}
+
+// when a name carries several sources the first one in this list wins
+var sourceOrder = []string{"REMUX", "BluRay", "WEB-DL", "WEBRip", "WEB", "HDTV", "HDLight", "HDRip", "DVD", "SCR", "TC", "TS", "CAM"}
+
+var camSources = []string{"CAM", "TS", "TC", "SCR"}
+
+var codecs = map[string]string{
+	"4":    "x264",
+	"5":    "x265",
+	"avc":  "x264",
+	"hevc": "x265",
+	"av1":  "AV1",
+	"xvid": "XviD",
+	"divx": "DivX",
+	"vp9":  "VP9",
+}
+
+var hdrs = map[string]string{
+	"hdr10+":      "HDR10+",
+	"hdr10plus":   "HDR10+",
+	"hdr10":       "HDR10",
+	"hdr":         "HDR",
+	"dolbyvision": "DV",
+	"dovi":        "DV",
+	"hlg":         "HLG",
+}
+
+var audios = map[string]string{
+	"ddp":     "DDP",
+	"dd+":     "DDP",
+	"eac3":    "DDP",
+	"dd":      "DD",
+	"ac3":     "DD",
+	"aac":     "AAC",
+	"flac":    "FLAC",
+	"opus":    "Opus",
+	"mp3":     "MP3",
+	"truehd":  "TrueHD",
+	"atmos":   "Atmos",
+	"dtshd":   "DTS-HD",
+	"dtshdma": "DTS-HD MA",
+	"dtsx":    "DTS:X",
+	"dts":     "DTS",
+}
+
+type tagKind int
+
+const (
+	kindLanguage tagKind = iota
+	kindSource
+	kindResolution
+	kindHDR
+	kindFlag
+)
+
+type tag struct {
+	kind  tagKind
+	value string
+}
+
+// single words that are only trusted after the title, they are too short or
+// too common to be told apart from a title word otherwise
+var wordTags = map[string]tag{
+	"multi":      {kindLanguage, "MULTI"},
+	"truefrench": {kindLanguage, "TRUEFRENCH"},
+	"french":     {kindLanguage, "FRENCH"},
+	"fr":         {kindLanguage, "FRENCH"},
+	"vf":         {kindLanguage, "VF"},
+	"vff":        {kindLanguage, "VFF"},
+	"vfq":        {kindLanguage, "VFQ"},
+	"vfi":        {kindLanguage, "VFI"},
+	"vf2":        {kindLanguage, "VF2"},
+	"vostfr":     {kindLanguage, "VOSTFR"},
+	"subfrench":  {kindLanguage, "VOSTFR"},
+	"vost":       {kindLanguage, "VOST"},
+	"vo":         {kindLanguage, "VO"},
+	"english":    {kindLanguage, "ENGLISH"},
+	"eng":        {kindLanguage, "ENGLISH"},
+	"spanish":    {kindLanguage, "SPANISH"},
+	"esp":        {kindLanguage, "SPANISH"},
+	"castellano": {kindLanguage, "SPANISH"},
+	"latino":     {kindLanguage, "LATINO"},
+	"italian":    {kindLanguage, "ITALIAN"},
+	"ita":        {kindLanguage, "ITALIAN"},
+	"german":     {kindLanguage, "GERMAN"},
+	"ger":        {kindLanguage, "GERMAN"},
+	"deutsch":    {kindLanguage, "GERMAN"},
+	"japanese":   {kindLanguage, "JAPANESE"},
+	"jap":        {kindLanguage, "JAPANESE"},
+	"jpn":        {kindLanguage, "JAPANESE"},
+
+	"web": {kindSource, "WEB"},
+	"bd":  {kindSource, "BluRay"},
+	"dvd": {kindSource, "DVD"},
+	"cam": {kindSource, "CAM"},
+	"ts":  {kindSource, "TS"},
+	"tc":  {kindSource, "TC"},
+	"scr": {kindSource, "SCR"},
+
+	"hd": {kindResolution, "720p"},
+	"sd": {kindResolution, "480p"},
+
+	"dv": {kindHDR, "DV"},
+
+	"complete":  {kindFlag, "complete"},
+	"integrale": {kindFlag, "complete"},
+	"proper":    {kindFlag, "proper"},
+	"repack":    {kindFlag, "repack"},
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// SearchBackend is an indexer manager we can send torznab searches to.
+type SearchBackend interface {
+	Caps(ctx context.Context, indexer types.Indexer) (types.TorznabCaps, error)
+	Search(ctx context.Context, query types.SearchQuery, type_ string, indexer types.Indexer) ([]types.ItemsParsed, error)
+}
+
+func newSearchBackend(server types.Server) SearchBackend {
+	switch server.Backend {
+	case "prowlarr":
+		return &prowlarr{server: server}
+	}
+	return &jackett{server: server}
+}
+
+// textSearches are the free text queries sent to indexers without ID based
+// search support
+func textSearches(type_ string, name string, year string, s int, e int, abs bool, absEpisode int) []types.SearchQuery {
+	queries := []string{fmt.Sprintf("%s %s", name, year)}
+	if type_ != "movie" {
+		queries = []string{
+			fmt.Sprintf("%s S%02d", name, s),
+			fmt.Sprintf("%s integrale", name),
+			fmt.Sprintf("%s batch", name),
+			fmt.Sprintf("%s complet", name),
+			fmt.Sprintf("%s S%02dE%02d", name, s, e),
+		}
+		if s == 1 {
+			queries = append(queries, fmt.Sprintf("%s E%02d", name, e), fmt.Sprintf("%s %02d", name, e))
+		}
+		if abs {
+			queries = append(queries, fmt.Sprintf("%s E%03d", name, absEpisode), fmt.Sprintf("%s %03d", name, absEpisode))
+		}
+	}
+
+	searches := make([]types.SearchQuery, 0, len(queries))
+	for _, query := range queries {
+		searches = append(searches, types.SearchQuery{Type: "search", Query: query})
+	}
+	return searches
+}
+
+// idSearches are the imdb based queries, the season only one catches packs
+func idSearches(type_ string, imdbId string, s int, e int) []types.SearchQuery {
+	if !strings.HasPrefix(imdbId, "tt") {
+		return nil
+	}
+
+	if type_ == "movie" {
+		return []types.SearchQuery{{Type: "movie", ImdbID: imdbId}}
+	}
+
+	return []types.SearchQuery{
+		{Type: "tvsearch", ImdbID: imdbId, Season: s, Episode: e},
+		{Type: "tvsearch", ImdbID: imdbId, Season: s},
+	}
+}
+
+// caps rarely change, failures are retried sooner
+const capsTTL = 6 * time.Hour
+const capsErrorTTL = 10 * time.Minute
+
+type cachedCaps struct {
+	caps    types.TorznabCaps
+	err     error
+	fetched time.Time
+}
+
+var capsCache = sync.Map{}
+
+// indexerCaps returns the caps of an indexer on a host, from cache when fresh.
+func indexerCaps(ctx context.Context, server types.Server, backend SearchBackend, indexer types.Indexer) (types.TorznabCaps, error) {
+	key := fmt.Sprintf("%s|%s", server.Host, indexer.ID)
+
+	if cached, ok := capsCache.Load(key); ok {
+		entry := cached.(cachedCaps)
+		ttl := capsTTL
+		if entry.err != nil {
+			ttl = capsErrorTTL
+		}
+		if time.Since(entry.fetched) < ttl {
+			return entry.caps, entry.err
+		}
+	}
+
+	caps, err := backend.Caps(ctx, indexer)
+	if ctx.Err() != nil {
+		// not cached, the indexer was not given its time
+		return caps, err
+	}
+	capsCache.Store(key, cachedCaps{caps: caps, err: err, fetched: time.Now()})
+	return caps, err
+}
+
+// idQueries keeps the ID based searches the indexer caps allow
+func idQueries(caps types.TorznabCaps, queries []types.SearchQuery) []types.SearchQuery {
+	var supported []types.SearchQuery
+	for _, query := range queries {
+		params := []string{"imdbid"}
+		if query.Season > 0 {
+			params = append(params, "season")
+		}
+		if query.Episode > 0 {
+			params = append(params, "ep")
+		}
+
+		switch {
+		case query.Type == "tvsearch" && caps.Searching.TvSearch.Supports(params...):
+			supported = append(supported, query)
+		case query.Type == "movie" && caps.Searching.MovieSearch.Supports(params...):
+			supported = append(supported, query)
+		}
+	}
+	return supported
+}
+
+// searchIndexer runs the searches of one indexer: the ID based ones when the
+// indexer supports them all, on the host whose caps said so, the text ones
+// on any host otherwise or when the ID ones fail or find nothing.
+func searchIndexer(ctx context.Context, indexer types.Indexer, type_ string, idSearches []types.SearchQuery, textSearches []types.SearchQuery) []types.ItemsParsed {
+	if len(idSearches) > 0 && indexer.Path == "" {
+		if server, ok := serverPoolInstance().pick(nil); ok {
+			if items, ok := searchIndexerIds(ctx, server, indexer, type_, idSearches); ok {
+				return items
+			}
+		}
+	}
+
+	found := fanOut(ctx, textSearches, queryConcurrency, func(ctx context.Context, query types.SearchQuery) []types.ItemsParsed {
+		return fetchTorrent(ctx, query, type_, indexer)
+	})
+
+	return flatten(found)
+}
+
+// searchIndexerIds runs the ID based searches on the host when its caps allow
+// them. ok is false when they can't be used or found nothing.
+func searchIndexerIds(ctx context.Context, server types.Server, indexer types.Indexer, type_ string, idSearches []types.SearchQuery) ([]types.ItemsParsed, bool) {
+	start := time.Now()
+	caps, err := indexerCaps(ctx, server, newSearchBackend(server), indexer)
+	if err != nil {
+		// neither a search cut by the request budget nor an indexer the host
+		// doesn't serve say anything about the host
+		if ctx.Err() == nil && hostFailure(err) {
+			serverPoolInstance().report(server.Host, time.Since(start), err)
+		}
+		logError("caps failed", err, "host", server.Host, "indexer", indexer.ID)
+		return nil, false
+	}
+
+	supported := idQueries(caps, idSearches)
+	if len(supported) != len(idSearches) {
+		return nil, false
+	}
+
+	// the other hosts may not know the indexer IDs, no failover
+	found := fanOut(ctx, supported, queryConcurrency, func(ctx context.Context, query types.SearchQuery) []types.ItemsParsed {
+		items, err := searchHost(ctx, server, query, type_, indexer)
+		if err != nil && ctx.Err() == nil {
+			logError("id search failed", err, "host", server.Host, "indexer", indexer.ID)
+		}
+		return items
+	})
+
+	items := flatten(found)
+	if len(items) == 0 {
+		fmt.Printf("No ID result for %s on %s, searching by title\n", indexer.ID, server.Host)
+		return nil, false
+	}
+	return items, true
+}
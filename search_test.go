@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+var testIndexer = types.Indexer{ID: "yggtorrent"}
+
+func searchTestEpisode(ctx context.Context) []types.ItemsParsed {
+	return searchIndexer(ctx, testIndexer, "series", idSearches("series", testImdb, 1, 2), textSearches("series", "Show", "2011", 1, 2, false, 0))
+}
+
+// with both backends in the pool, the ID searches only go to the host whose
+// caps were read, whichever the pool picks
+func TestSearchIndexerMixedPool(t *testing.T) {
+	useTestCaches(t)
+	torrents := testTorrents(t)
+
+	tests := []struct {
+		name       string
+		jackettIds bool
+	}{
+		{"ids on jackett", true},
+		{"ids on prowlarr", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jackett := fakeJackett(t, torrents, fakeOptions{idSearch: test.jackettIds})
+			prowlarr := fakeProwlarr(t, torrents, fakeOptions{idSearch: !test.jackettIds})
+			useServers(t, jackett.Server, prowlarr.Server)
+
+			withIds, withoutIds := jackett, prowlarr
+			if !test.jackettIds {
+				withIds, withoutIds = prowlarr, jackett
+			}
+
+			for i := 0; i < 30; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				items := searchTestEpisode(ctx)
+				cancel()
+
+				if len(items) == 0 {
+					t.Fatalf("search %d found nothing", i)
+				}
+			}
+
+			if withoutIds.idSearches.Load() != 0 {
+				t.Errorf("%d ID searches sent to the host without ID caps", withoutIds.idSearches.Load())
+			}
+			if withIds.idSearches.Load() == 0 {
+				t.Error("no ID search sent to the host with ID caps")
+			}
+		})
+	}
+}
+
+func TestSearchIndexerFallback(t *testing.T) {
+	useTestCaches(t)
+	torrents := testTorrents(t)
+
+	tests := []struct {
+		name string
+		opts fakeOptions
+		text bool
+	}{
+		{"id results", fakeOptions{idSearch: true}, false},
+		{"no id result", fakeOptions{idSearch: true, emptyIdSearch: true}, true},
+		{"failed id search", fakeOptions{idSearch: true, failIdSearch: true}, true},
+		{"no id caps", fakeOptions{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			host := fakeJackett(t, torrents, test.opts)
+			useServers(t, host.Server)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if items := searchTestEpisode(ctx); len(items) == 0 {
+				t.Error("found nothing")
+			}
+			if text := host.textSearches.Load() > 0; text != test.text {
+				t.Errorf("%d title searches, want some: %v", host.textSearches.Load(), test.text)
+			}
+		})
+	}
+}
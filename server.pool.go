@@ -0,0 +1,165 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// consecutive failures before a host is taken out of the rotation, and for how long
+const poolBreakerThreshold = 3
+const poolBreakerCooldown = time.Minute
+
+// weight of the last request in the latency moving average
+const poolLatencyAlpha = 0.3
+
+type serverState struct {
+	server              types.Server
+	requests            int
+	failures            int
+	consecutiveFailures int
+	latency             time.Duration
+	openUntil           time.Time
+	lastError           string
+}
+
+// serverPool holds the servers.db hosts for the whole process life and keeps
+// track of how well they answer.
+type serverPool struct {
+	mu     sync.Mutex
+	states []*serverState
+	now    func() time.Time
+}
+
+var pool *serverPool
+var poolOnce sync.Once
+
+func serverPoolInstance() *serverPool {
+	poolOnce.Do(func() {
+		pool = newServerPool(getServers())
+	})
+	return pool
+}
+
+func newServerPool(servers []types.Server) *serverPool {
+	p := &serverPool{now: time.Now}
+	for _, server := range servers {
+		p.states = append(p.states, &serverState{server: server})
+	}
+	return p
+}
+
+// pick returns a host not in exclude. Healthy hosts are chosen at random,
+// weighted by their latency; when every host is broken the one closest to
+// the end of its cooldown gets a trial request.
+func (p *serverPool) pick(exclude []string) (types.Server, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var healthy []*serverState
+	var broken *serverState
+
+	for _, state := range p.states {
+		if slices.Contains(exclude, state.server.Host) {
+			continue
+		}
+		if now.After(state.openUntil) {
+			healthy = append(healthy, state)
+		} else if broken == nil || state.openUntil.Before(broken.openUntil) {
+			broken = state
+		}
+	}
+
+	if len(healthy) == 0 {
+		if broken == nil {
+			return types.Server{}, false
+		}
+		return broken.server, true
+	}
+
+	weights := make([]float64, len(healthy))
+	total := 0.0
+	for i, state := range healthy {
+		// unknown hosts get a chance as if they were average
+		latency := state.latency
+		if latency == 0 {
+			latency = time.Second
+		}
+		weights[i] = 1 / latency.Seconds()
+		total += weights[i]
+	}
+
+	r := rand.Float64() * total
+	for i, weight := range weights {
+		if r < weight {
+			return healthy[i].server, true
+		}
+		r -= weight
+	}
+	return healthy[len(healthy)-1].server, true
+}
+
+// report records the outcome of a request sent to a host
+func (p *serverPool) report(host string, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, state := range p.states {
+		if state.server.Host != host {
+			continue
+		}
+
+		state.requests++
+		if err != nil {
+			state.failures++
+			state.consecutiveFailures++
+			state.lastError = err.Error()
+			if state.consecutiveFailures >= poolBreakerThreshold {
+				state.openUntil = p.now().Add(poolBreakerCooldown)
+			}
+			return
+		}
+
+		state.consecutiveFailures = 0
+		state.openUntil = time.Time{}
+		if state.latency == 0 {
+			state.latency = latency
+		} else {
+			state.latency = time.Duration(poolLatencyAlpha*float64(latency) + (1-poolLatencyAlpha)*float64(state.latency))
+		}
+		return
+	}
+}
+
+func (p *serverPool) snapshot() []types.ServerHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	res := make([]types.ServerHealth, 0, len(p.states))
+	for _, state := range p.states {
+		health := types.ServerHealth{
+			Host:                state.server.Host,
+			Backend:             state.server.Backend,
+			Healthy:             now.After(state.openUntil),
+			Requests:            state.requests,
+			Failures:            state.failures,
+			ConsecutiveFailures: state.consecutiveFailures,
+			LatencyMs:           state.latency.Milliseconds(),
+			LastError:           state.lastError,
+		}
+		if state.requests > 0 {
+			health.ErrorRate = float64(state.failures) / float64(state.requests)
+		}
+		if !health.Healthy {
+			openUntil := state.openUntil
+			health.OpenUntil = &openUntil
+		}
+		res = append(res, health)
+	}
+	return res
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+var errHostDown = errors.New("host down")
+
+// picked tells which hosts pick returned over enough draws to see them all
+func picked(p *serverPool, exclude ...string) map[string]bool {
+	hosts := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		if server, ok := p.pick(exclude); ok {
+			hosts[server.Host] = true
+		}
+	}
+	return hosts
+}
+
+func TestServerPoolBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := newServerPool([]types.Server{{Host: "a"}, {Host: "b"}})
+	p.now = func() time.Time { return now }
+
+	steps := []struct {
+		name    string
+		elapsed time.Duration
+		reports []error // sent for host a
+		exclude []string
+		want    []string
+		healthy bool // host a in the snapshot
+	}{
+		{"closed", 0, nil, nil, []string{"a", "b"}, true},
+		{"under the threshold", 0, []error{errHostDown, errHostDown}, nil, []string{"a", "b"}, true},
+		{"open", 0, []error{errHostDown}, nil, []string{"b"}, false},
+		{"trial when the others are excluded", 0, nil, []string{"b"}, []string{"a"}, false},
+		{"still cooling down", poolBreakerCooldown / 2, nil, nil, []string{"b"}, false},
+		{"half-open after the cooldown", poolBreakerCooldown, nil, nil, []string{"a", "b"}, true},
+		{"open again on a failed trial", 0, []error{errHostDown}, nil, []string{"b"}, false},
+		{"closed by a successful trial", poolBreakerCooldown + time.Second, []error{nil}, nil, []string{"a", "b"}, true},
+		{"failures counted from zero", 0, []error{errHostDown, errHostDown}, nil, []string{"a", "b"}, true},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.elapsed)
+		for _, err := range step.reports {
+			p.report("a", 100*time.Millisecond, err)
+		}
+
+		hosts := picked(p, step.exclude...)
+		if len(hosts) != len(step.want) {
+			t.Errorf("%s: picked %v, want %v", step.name, hosts, step.want)
+		}
+		for _, host := range step.want {
+			if !hosts[host] {
+				t.Errorf("%s: %s never picked, want %v", step.name, host, step.want)
+			}
+		}
+
+		if healthy := p.snapshot()[0].Healthy; healthy != step.healthy {
+			t.Errorf("%s: healthy %v, want %v", step.name, healthy, step.healthy)
+		}
+	}
+
+	if _, ok := p.pick([]string{"a", "b"}); ok {
+		t.Error("picked an excluded host")
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// how long an answer is fresh. Stale answers are still served, for
+// staleFor, while a new search runs in the background.
+const (
+	airingStreamsTTL = 6 * time.Hour
+	endedStreamsTTL  = 3 * 24 * time.Hour
+	newMoviesTTL     = 12 * time.Hour
+	oldMoviesTTL     = 7 * 24 * time.Hour
+	noStreamsTTL     = 30 * time.Minute
+	staleFor         = 7 * 24 * time.Hour
+)
+
+// streamCacheTTL picks the freshness of an answer. Cinemeta years are
+// "2019–" for shows still airing and "2011–2019" for ended ones.
+func streamCacheTTL(type_ string, year string, empty bool) time.Duration {
+	if empty {
+		return noStreamsTTL
+	}
+
+	start, end, ranged := strings.Cut(strings.ReplaceAll(year, "–", "-"), "-")
+	first, _ := strconv.Atoi(strings.TrimSpace(start))
+	recent := first == 0 || first >= time.Now().Year()-1
+
+	if type_ == "movie" {
+		if recent {
+			return newMoviesTTL
+		}
+		return oldMoviesTTL
+	}
+
+	if recent || (ranged && strings.TrimSpace(end) == "") {
+		return airingStreamsTTL
+	}
+	return endedStreamsTTL
+}
+
+type streamCache struct {
+	cache Cache
+	// searches running, by cache key
+	flights singleflight.Group
+}
+
+func newStreamCache(cache Cache) *streamCache {
+	return &streamCache{cache: cache}
+}
+
+// answers are kept under a versioned key, the ones from before 0-based file
+// indexes point at the wrong files
+func streamKey(key string) string {
+	return "streams:v2:" + key
+}
+
+// get reads an answer. Entries from before the timestamps have none and are
+// stale right away.
+func (s *streamCache) get(key string) (types.CachedStreams, bool) {
+	var cached types.CachedStreams
+	found, err := s.cache.Get(streamKey(key), &cached)
+	if err != nil {
+		// a broken entry is just a miss, it gets overwritten
+		logError("cache read failed", err, "key", key)
+		return types.CachedStreams{}, false
+	}
+	return cached, found
+}
+
+// set keeps an answer for its ttl plus the stale window, nothing is kept when
+// ttl is 0.
+func (s *streamCache) set(key string, streams types.StreamMeta, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	entry := types.CachedStreams{Streams: streams.Streams, Created: time.Now(), TTL: int64(ttl / time.Second)}
+	if errCache := s.cache.Set(streamKey(key), entry, ttl+staleFor); errCache != nil {
+		logError("cache write failed", errCache, "key", key)
+	}
+}
+
+// search runs the search of an answer and caches it. Identical requests
+// arriving meanwhile wait for it instead of searching too.
+func (s *streamCache) search(ctx context.Context, key string, cfg types.UserConfig, type_ string, id string, basePath string) types.StreamMeta {
+	result, _, shared := s.flights.Do(key, func() (any, error) {
+		// runs on the budget of the request that started it, the ones
+		// joining later get the same answer
+		streams, ttl := searchStreams(ctx, cfg, type_, id, basePath)
+		s.set(key, streams, ttl)
+		return streams, nil
+	})
+	if shared {
+		fmt.Printf("Shared search %s\n", key)
+	}
+	return result.(types.StreamMeta)
+}
+
+// refresh searches again in the background, joining the search of the key
+// when one is running. The strings are copied, fiber reuses the request
+// buffers after the handler.
+func (s *streamCache) refresh(key string, cfg types.UserConfig, type_ string, id string, basePath string) {
+	key, type_, id, basePath = strings.Clone(key), strings.Clone(type_), strings.Clone(id), strings.Clone(basePath)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), requestBudget())
+		defer cancel()
+
+		fmt.Printf("Refreshing %s\n", key)
+		s.search(ctx, key, cfg, type_, id, basePath)
+	}()
+}
+
+// withBaseURL turns the relative resolve links of an answer into absolute
+// ones for the host the request came through. Answers are shared, the
+// streams are copied.
+func withBaseURL(streams []types.TorrentStreams, baseURL string) types.StreamMeta {
+	res := types.StreamMeta{Streams: make([]types.TorrentStreams, len(streams))}
+	for i, stream := range streams {
+		if strings.HasPrefix(stream.URL, "/") {
+			stream.URL = baseURL + stream.URL
+		}
+		res.Streams[i] = stream
+	}
+	return res
+}
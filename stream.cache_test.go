@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// identical requests arriving during a search wait for it
+func TestStreamCacheSharedSearch(t *testing.T) {
+	useTestCaches(t)
+	torrents := testTorrents(t)
+	opts := fakeOptions{searchDelay: 300 * time.Millisecond}
+	id := testImdb + ":1:2"
+
+	search := func(host *fakeIndexer, requests int) []types.StreamMeta {
+		useServers(t, host.Server)
+		cache := newStreamCache(appCache())
+
+		answers := make([]types.StreamMeta, requests)
+		var wg sync.WaitGroup
+		for i := range answers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				answers[i] = cache.search(ctx, id, testConfig, "series", id, "")
+			}()
+		}
+		wg.Wait()
+		return answers
+	}
+
+	single := fakeJackett(t, torrents, opts)
+	search(single, 1)
+	if single.textSearches.Load() == 0 {
+		t.Fatal("no search sent")
+	}
+
+	shared := fakeJackett(t, torrents, opts)
+	answers := search(shared, 10)
+
+	if shared.textSearches.Load() != single.textSearches.Load() {
+		t.Errorf("%d indexer searches for 10 identical requests, one request sends %d", shared.textSearches.Load(), single.textSearches.Load())
+	}
+	for i, answer := range answers {
+		if len(answer.Streams) == 0 {
+			t.Errorf("request %d got no stream", i)
+		}
+	}
+}
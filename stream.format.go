@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/daniwalter001/jackett_fiber/release"
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+const addonName = "GoDon"
+
+// streamDetails is what the stream name and title templates can use
+type streamDetails struct {
+	Addon     string
+	Debrid    string // [RD+], empty without debrid
+	Quality   string // 4k, FHD, HD, SD
+	Release   string // torrent title
+	FileName  string
+	Size      string
+	Seeders   string
+	Peers     string
+	Indexer   string
+	Languages string
+	Codec     string
+	Source    string
+	HDR       string
+	Audio     string
+}
+
+type streamTemplate struct {
+	name  *template.Template
+	title *template.Template
+}
+
+func newStreamTemplate(id string, name string, title string) streamTemplate {
+	return streamTemplate{
+		name:  template.Must(template.New(id + ".name").Parse(name)),
+		title: template.Must(template.New(id + ".title").Parse(title)),
+	}
+}
+
+const defaultStreamTemplate = "detailed"
+
+// stream templates users can pick from, empty lines are dropped once rendered
+var streamTemplates = map[string]streamTemplate{
+	"detailed": newStreamTemplate("detailed",
+		`{{with .Debrid}}{{.}} {{end}}{{.Addon}} {{.Quality}}`,
+		`{{.FileName}}
+{{with .Size}}💾 {{.}}{{end}}{{with .Seeders}} 👤 {{.}}{{end}}{{with .Peers}}/{{.}}{{end}}{{with .Indexer}} ⚙️ {{.}}{{end}}
+{{with .Languages}}🌐 {{.}} {{end}}{{with .Source}}📀 {{.}} {{end}}{{with .Codec}}🎞️ {{.}}{{end}}{{with .HDR}} {{.}}{{end}}`),
+	"compact": newStreamTemplate("compact",
+		`{{with .Debrid}}{{.}} {{end}}{{.Addon}} {{.Quality}}`,
+		`{{.FileName}}
+{{with .Size}}{{.}} · {{end}}{{with .Seeders}}{{.}} seeders{{end}}{{with .Languages}} · {{.}}{{end}}`),
+	"torrentio": newStreamTemplate("torrentio",
+		`{{with .Debrid}}{{.}} {{end}}{{.Addon}}
+{{.Quality}}`,
+		`{{.Release}}
+{{.FileName}}
+{{with .Seeders}}👤 {{.}} {{end}}{{with .Size}}💾 {{.}} {{end}}{{with .Indexer}}⚙️ {{.}}{{end}}
+{{.Languages}}`),
+}
+
+var qualityLabels = map[int]string{
+	4: "4k",
+	3: "FHD",
+	2: "HD",
+	1: "SD",
+}
+
+// streamPresentation builds the details shown for one file of a torrent. size
+// is the file size, the torrent size is used when it is unknown.
+func streamPresentation(item types.ItemsParsed, fileName string, size int64, debrid string) streamDetails {
+	info := release.Parse(item.Title)
+	file := release.Parse(fileName)
+
+	if size <= 0 {
+		size = item.Size
+	}
+
+	details := streamDetails{
+		Addon:     addonName,
+		Debrid:    debrid,
+		Quality:   qualityLabels[max(info.ResolutionRank(), file.ResolutionRank())],
+		Release:   item.Title,
+		FileName:  path.Base(fileName),
+		Seeders:   item.Seeders,
+		Peers:     item.Peers,
+		Indexer:   item.Tracker,
+		Languages: strings.Join(info.Languages, " / "),
+		Codec:     info.Codec,
+		Source:    info.Source,
+		HDR:       strings.Join(info.HDR, " "),
+		Audio:     strings.Join(info.Audio, " "),
+	}
+
+	if size > 0 {
+		details.Size = strings.TrimPrefix(getSize(int(size)), "💾 ")
+	}
+	if details.Indexer == "" {
+		details.Indexer = item.Indexer
+	}
+	if details.Codec == "" {
+		details.Codec = file.Codec
+	}
+	if details.FileName == "." {
+		details.FileName = item.Title
+	}
+
+	return details
+}
+
+// formatStream renders the name and title of a stream with the user template,
+// the default one when unknown.
+func formatStream(templateName string, details streamDetails) (string, string) {
+	tmpl, ok := streamTemplates[templateName]
+	if !ok {
+		tmpl = streamTemplates[defaultStreamTemplate]
+	}
+
+	return renderLines(tmpl.name, details), renderLines(tmpl.title, details)
+}
+
+func renderLines(tmpl *template.Template, details streamDetails) string {
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, details); err != nil {
+		logError("stream template failed", err, "template", tmpl.Name())
+		return details.Release
+	}
+
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
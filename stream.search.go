@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// searchStreams answers a stream request, from the stremio id to the ranked
+// streams. The ttl is how long the answer can be cached, 0 when it must not
+// be as it comes from a failure. The indexer searches get searchBudgetShare of
+// the ctx deadline, the debrid check and torrent reads the rest; what is ready
+// when it expires is answered and only cached for partialStreamsTTL. Resolve
+// links are paths under basePath, the host is added when serving.
+func searchStreams(ctx context.Context, cfg types.UserConfig, type_ string, id string, basePath string) (types.StreamMeta, time.Duration) {
+	var s, e, abs_season, abs_episode int
+	var tt string
+	abs := "false"
+
+	// stremio shows nothing rather than an error, so failures end up there
+	noStreams := types.StreamMeta{Streams: []types.TorrentStreams{}}
+
+	var tmp []string
+
+	if strings.Contains(id, "kitsu") {
+		var errKitsu error
+		tmp, errKitsu = getImdbFromKitsuCached(ctx, id)
+		if errKitsu != nil {
+			logError("kitsu lookup failed", errKitsu, "id", id)
+			return noStreams, 0
+		}
+	} else {
+		tmp = strings.Split(id, ":")
+	}
+
+	fmt.Println(tmp)
+
+	tt = tmp[0]
+	if len(tmp) > 2 {
+		s, _ = strconv.Atoi(tmp[1])
+		e, _ = strconv.Atoi(tmp[2])
+		if len(tmp) > 5 {
+			abs_season, _ = strconv.Atoi(tmp[3])
+			abs_episode, _ = strconv.Atoi(tmp[4])
+			abs = tmp[5]
+		}
+	}
+
+	name, year, errMeta := getMetaCached(ctx, tt, type_)
+	if errMeta != nil {
+		logError("meta lookup failed", errMeta, "id", tt, "type", type_)
+		return noStreams, 0
+	}
+
+	textQueries := textSearches(type_, name, year, s, e, abs == "true", abs_episode)
+	idQueries := idSearches(type_, tt, s, e)
+
+	indexers := resolveIndexers(cfg)
+	fmt.Printf("Indexers: %d\n", len(indexers))
+
+	searchCtx, cancelSearch := stageContext(ctx, searchBudgetShare)
+	found := fanOut(searchCtx, indexers, indexerConcurrency, func(ctx context.Context, indexer types.Indexer) []types.ItemsParsed {
+		return searchIndexer(ctx, indexer, type_, idQueries, textQueries)
+	})
+	partial := searchCtx.Err() == context.DeadlineExceeded
+	cancelSearch()
+	results := flatten(found)
+
+	sort.Slice(results, func(i, j int) bool {
+		iv, _ := strconv.Atoi(results[i].Peers)
+		jv, _ := strconv.Atoi(results[j].Peers)
+		return iv > jv
+	})
+
+	results = removeDuplicates(results)
+
+	results = filterResults(results, cfg)
+
+	var provider DebridProvider
+	// debrid cache status by infohash, .torrent links are checked once read
+	cachedHashes := map[string]bool{}
+	if cfg.DebridKey != "" {
+		var errProvider error
+		provider, errProvider = newDebridProvider(cfg.DebridProvider, cfg.DebridKey)
+		if errProvider != nil {
+			logError("debrid provider failed", upstreamError(stageDebrid, cfg.DebridProvider, 0, errProvider))
+		}
+	}
+
+	if provider != nil {
+		markCached(ctx, provider, results, cachedHashes)
+
+		if cfg.CachedOnly {
+			results = filter(results, func(item types.ItemsParsed) bool {
+				return item.Cached || item.InfoHash == ""
+			})
+		}
+	}
+
+	rankResults(results, cfg)
+
+	fmt.Printf("Results:%d\n", len(results))
+
+	maxRes := cfg.MaxResults
+
+	if len(results) > maxRes {
+		results = results[:maxRes]
+	}
+
+	fmt.Printf("Retenus:%d\n", len(results))
+
+	// each torrent keeps its rank, the ones that could not be read are
+	// dropped once they are all done
+	parsed := fanOut(ctx, results, torrentConcurrency, func(ctx context.Context, item types.ItemsParsed) types.ItemsParsed {
+		r, errTorrent := readTorrent(ctx, item)
+		if errTorrent != nil {
+			logError("torrent read failed", errTorrent, "indexer", item.Indexer)
+			return types.ItemsParsed{}
+		}
+		return r
+	})
+
+	parsedTorrentFiles := filter(parsed, func(item types.ItemsParsed) bool {
+		return len(item.TorrentData) != 0
+	})
+	// .torrent links only get their infohash once read
+	parsedTorrentFiles = removeDuplicates(parsedTorrentFiles)
+
+	if provider != nil {
+		if markCached(ctx, provider, parsedTorrentFiles, cachedHashes) {
+			rankResults(parsedTorrentFiles, cfg)
+		}
+
+		if cfg.CachedOnly {
+			parsedTorrentFiles = filter(parsedTorrentFiles, func(item types.ItemsParsed) bool {
+				return item.Cached
+			})
+		}
+	}
+
+	wanted := episodeRequest{Season: s, Episode: e, Abs: abs == "true", AbsSeason: abs_season, AbsEpisode: abs_episode}
+
+	// files come in the ranked torrent order
+	streams_ := types.StreamMeta{Streams: make([]types.TorrentStreams, 0)}
+	for _, element := range parsedTorrentFiles {
+		for _, el := range selectFiles(torrentFiles(element), element.Title, type_, wanted) {
+			fileIdx := el.Index
+			torrent := types.TorrentStreams{InfoHash: element.InfoHash, FileIdx: &fileIdx, Sources: torrentSources(element), BehaviorHints: types.BehaviorHints{BingeGroup: fmt.Sprintf("group-%s", tt), CountryWhitelist: []string{"en"}}}
+
+			debrid := ""
+			if cfg.DebridKey != "" && element.InfoHash != "" {
+				debrid = debridTag(cfg.DebridProvider, element.Cached)
+			}
+			torrent.Name, torrent.Title = formatStream(cfg.Template, streamPresentation(element, el.Path, el.Size, debrid))
+
+			if debrid != "" {
+				torrent.URL = fmt.Sprintf("%s/resolve/%s/%d/%d", basePath, element.InfoHash, fileIdx, el.Size)
+				torrent.InfoHash = ""
+				torrent.FileIdx = nil
+				torrent.Sources = nil
+			}
+			streams_.Streams = append(streams_.Streams, torrent)
+		}
+	}
+
+	fmt.Printf("Streams:%d\n", len(streams_.Streams))
+
+	ttl := streamCacheTTL(type_, year, len(streams_.Streams) == 0)
+	if partial || ctx.Err() != nil {
+		fmt.Printf("Budget spent, partial answer for %s\n", id)
+		ttl = min(ttl, partialStreamsTTL)
+	}
+	return streams_, ttl
+}
+
+// markCached sets the debrid cache status of the items. Only the hashes not in
+// known are sent to the provider, known keeps the answers. It tells whether
+// new hashes were checked.
+func markCached(ctx context.Context, provider DebridProvider, items []types.ItemsParsed, known map[string]bool) bool {
+	var hashes []string
+	for _, item := range items {
+		hash := strings.ToLower(item.InfoHash)
+		if _, checked := known[hash]; hash != "" && !checked {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	if len(hashes) > 0 {
+		cached := checkCachedBatch(ctx, provider, hashes)
+		for _, hash := range hashes {
+			known[hash] = cached[hash]
+		}
+	}
+
+	for i := range items {
+		items[i].Cached = known[strings.ToLower(items[i].InfoHash)]
+	}
+	return len(hashes) > 0
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+const testImdb = "tt0944947"
+
+// testTorrent is a .torrent served by the fake indexers
+type testTorrent struct {
+	title string
+	files []metainfo.FileInfo
+	data  []byte
+	hash  string
+}
+
+func newTestTorrent(t *testing.T, title string, paths ...string) testTorrent {
+	info := metainfo.Info{Name: title, PieceLength: 1 << 14, Pieces: make([]byte, 20)}
+	for i, path := range paths {
+		info.Files = append(info.Files, metainfo.FileInfo{Path: []string{path}, Length: int64(i+1) << 20})
+	}
+
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi := metainfo.MetaInfo{InfoBytes: infoBytes, Announce: "udp://tracker.test:1337/announce"}
+
+	var buf bytes.Buffer
+	if err := mi.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return testTorrent{title: title, files: info.Files, data: buf.Bytes(), hash: mi.HashInfoBytes().HexString()}
+}
+
+// the season pack holds the wanted episode as its second file, the single
+// episode as its only one
+func testTorrents(t *testing.T) []testTorrent {
+	return []testTorrent{
+		newTestTorrent(t, "Show S01 1080p WEB-DL", "Show.S01E01.1080p.mkv", "Show.S01E02.1080p.mkv", "Show.S01E03.1080p.mkv", "Show.nfo"),
+		newTestTorrent(t, "Show S01E02 720p HDTV", "Show.S01E02.720p.mkv"),
+	}
+}
+
+// useServers points the server pool at the fake hosts for the test.
+func useServers(t *testing.T, servers ...types.Server) {
+	poolOnce.Do(func() {})
+	previous := pool
+	pool = newServerPool(servers)
+	t.Cleanup(func() { pool = previous })
+}
+
+// useTestCaches keeps everything in memory, with the meta of the show known
+// so cinemeta is never called.
+func useTestCaches(t *testing.T) {
+	t.Setenv("REDIS_URL", "")
+	t.Setenv("CACHE_BACKEND", cacheMemory)
+	t.Setenv("TORRENT_CACHE_BACKEND", cacheMemory)
+
+	if err := appCache().Set("meta:series:"+testImdb, metaEntry{Name: "Show", Year: "2011–2019"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveTorrents serves the .torrent files under /dl/<index>, block makes
+// them wait until the request ends.
+func serveTorrents(mux *http.ServeMux, torrents []testTorrent, block chan struct{}, reads *atomic.Int32) {
+	mux.HandleFunc("GET /dl/{index}", func(w http.ResponseWriter, r *http.Request) {
+		reads.Add(1)
+		if block != nil {
+			select {
+			case <-block:
+			case <-r.Context().Done():
+			}
+			return
+		}
+
+		var index int
+		fmt.Sscan(r.PathValue("index"), &index)
+		w.Write(torrents[index].data)
+	})
+}
+
+// fakeIndexer is a fake Jackett or Prowlarr host and what it was asked
+type fakeIndexer struct {
+	types.Server
+	caps         atomic.Int32
+	idSearches   atomic.Int32
+	textSearches atomic.Int32
+	reads        atomic.Int32
+}
+
+type fakeOptions struct {
+	idSearch      bool // the caps allow imdb searches
+	emptyIdSearch bool // which find nothing
+	failIdSearch  bool // or fail
+	blockSearch   bool // searches wait until the request ends
+	blockTorrents bool // torrent downloads too
+	searchDelay   time.Duration
+}
+
+const fakeCaps = `<?xml version="1.0" encoding="UTF-8"?><caps><searching><search available="yes" supportedParams="q"/>` +
+	`<tv-search available="yes" supportedParams="q,season,ep,imdbid"/><movie-search available="yes" supportedParams="q,imdbid"/></searching></caps>`
+
+// newFakeIndexer starts the host, the backend routes come from register
+func newFakeIndexer(t *testing.T, torrents []testTorrent, backend string, opts fakeOptions, register func(*http.ServeMux, *fakeIndexer, chan struct{})) *fakeIndexer {
+	block := make(chan struct{})
+	mux := http.NewServeMux()
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	// registered after Close so that it runs first, blocked handlers would
+	// keep Close waiting
+	t.Cleanup(func() { close(block) })
+
+	fake := &fakeIndexer{Server: types.Server{Host: server.URL, ApiKey: "key", Backend: backend}}
+	register(mux, fake, block)
+
+	var torrentBlock chan struct{}
+	if opts.blockTorrents {
+		torrentBlock = block
+	}
+	serveTorrents(mux, torrents, torrentBlock, &fake.reads)
+
+	return fake
+}
+
+// answer counts a search and tells if it gets results. Blocked ones wait
+// for the end of the request; failed ones are answered here, with done set.
+func (f *fakeIndexer) answer(w http.ResponseWriter, r *http.Request, opts fakeOptions, id bool, block chan struct{}) (results bool, done bool) {
+	if id {
+		f.idSearches.Add(1)
+	} else {
+		f.textSearches.Add(1)
+	}
+
+	select {
+	case <-time.After(opts.searchDelay):
+	case <-r.Context().Done():
+	}
+
+	switch {
+	case opts.blockSearch:
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+		return false, false
+	case id && opts.failIdSearch:
+		http.Error(w, "indexer down", http.StatusInternalServerError)
+		return false, true
+	}
+	return !id || !opts.emptyIdSearch, false
+}
+
+func (f *fakeIndexer) serveCaps(w http.ResponseWriter, r *http.Request, opts fakeOptions) {
+	f.caps.Add(1)
+	if !opts.idSearch {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprint(w, fakeCaps)
+}
+
+// fakeJackett answers every torznab search with the test torrents, without
+// infohash like yggtorrent does.
+func fakeJackett(t *testing.T, torrents []testTorrent, opts fakeOptions) *fakeIndexer {
+	return newFakeIndexer(t, torrents, "jackett", opts, func(mux *http.ServeMux, fake *fakeIndexer, block chan struct{}) {
+		mux.HandleFunc("GET /api/v2.0/indexers/{id}/results/torznab/api", func(w http.ResponseWriter, r *http.Request) {
+			kind := r.URL.Query().Get("t")
+			if kind == "caps" {
+				fake.serveCaps(w, r, opts)
+				return
+			}
+
+			results, done := fake.answer(w, r, opts, kind == "tvsearch" || kind == "movie", block)
+			if done {
+				return
+			}
+
+			var items strings.Builder
+			if results {
+				for i, torrent := range torrents {
+					fmt.Fprintf(&items, `<item><title>%s</title><jackettindexer id="%s">YGG</jackettindexer><size>%d</size><link>%s/dl/%d</link>`+
+						`<torznab:attr name="seeders" value="%d"/><torznab:attr name="peers" value="%d"/></item>`,
+						torrent.title, r.PathValue("id"), 10<<20, fake.Host, i, 10-i, 20-i)
+				}
+			}
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed"><channel>%s</channel></rss>`, items.String())
+		})
+	})
+}
+
+// fakeProwlarr knows the indexer by name and answers the searches with the
+// test torrents.
+func fakeProwlarr(t *testing.T, torrents []testTorrent, opts fakeOptions) *fakeIndexer {
+	return newFakeIndexer(t, torrents, "prowlarr", opts, func(mux *http.ServeMux, fake *fakeIndexer, block chan struct{}) {
+		mux.HandleFunc("GET /api/v1/indexer", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, []types.ProwlarrIndexer{{ID: 3, Name: "YGGTorrent", DefinitionName: "yggtorrent", Enable: true, Protocol: "torrent"}})
+		})
+		mux.HandleFunc("GET /3/api", func(w http.ResponseWriter, r *http.Request) {
+			fake.serveCaps(w, r, opts)
+		})
+		mux.HandleFunc("GET /api/v1/search", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Api-Key") != "key" || r.URL.Query().Get("indexerIds") != "3" {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"message": "bad request"})
+				return
+			}
+
+			kind := r.URL.Query().Get("type")
+			answer, done := fake.answer(w, r, opts, kind == "tvsearch" || kind == "movie", block)
+			if done {
+				return
+			}
+
+			results := make([]types.ProwlarrSearchResult, 0, len(torrents))
+			if answer {
+				for i, torrent := range torrents {
+					results = append(results, types.ProwlarrSearchResult{
+						Title:       torrent.title,
+						Indexer:     "YGGTorrent",
+						Size:        10 << 20,
+						DownloadURL: fmt.Sprintf("%s/dl/%d", fake.Host, i),
+						Seeders:     10 - i,
+						Leechers:    10,
+						Protocol:    "torrent",
+					})
+				}
+			}
+			writeJSON(w, http.StatusOK, results)
+		})
+	})
+}
+
+var testConfig = types.UserConfig{Indexers: []string{"yggtorrent"}, MaxResults: 10}
+
+func TestSearchStreams(t *testing.T) {
+	useTestCaches(t)
+	torrents := testTorrents(t)
+
+	backends := map[string]func(*testing.T, []testTorrent, fakeOptions) *fakeIndexer{
+		"jackett":  fakeJackett,
+		"prowlarr": fakeProwlarr,
+	}
+
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			host := backend(t, torrents, fakeOptions{})
+			useServers(t, host.Server)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			streams, ttl := searchStreams(ctx, testConfig, "series", testImdb+":1:2", "")
+			if ttl != endedStreamsTTL {
+				t.Errorf("ttl %v, want %v", ttl, endedStreamsTTL)
+			}
+			if host.reads.Load() == 0 {
+				t.Error("no torrent read")
+			}
+
+			// the 0-based index of the episode in each torrent
+			want := map[string]int{torrents[0].hash: 1, torrents[1].hash: 0}
+			if len(streams.Streams) != len(want) {
+				t.Fatalf("%d streams, want %d: %+v", len(streams.Streams), len(want), streams.Streams)
+			}
+			for _, stream := range streams.Streams {
+				fileIdx, ok := want[stream.InfoHash]
+				if !ok {
+					t.Errorf("unexpected infohash %s", stream.InfoHash)
+					continue
+				}
+				if stream.FileIdx == nil {
+					t.Errorf("%s: no fileIdx, want %d", stream.InfoHash, fileIdx)
+				} else if *stream.FileIdx != fileIdx {
+					t.Errorf("%s: fileIdx %d, want %d", stream.InfoHash, *stream.FileIdx, fileIdx)
+				}
+				if len(stream.Sources) == 0 || stream.Sources[len(stream.Sources)-1] != "dht:"+stream.InfoHash {
+					t.Errorf("%s: sources %v", stream.InfoHash, stream.Sources)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchStreamsDeadline(t *testing.T) {
+	useTestCaches(t)
+	torrents := testTorrents(t)
+
+	tests := []struct {
+		name          string
+		blockSearch   bool
+		blockTorrents bool
+	}{
+		{"slow indexer", true, false},
+		{"slow torrent downloads", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			host := fakeJackett(t, torrents, fakeOptions{blockSearch: test.blockSearch, blockTorrents: test.blockTorrents})
+			useServers(t, host.Server)
+
+			budget := 500 * time.Millisecond
+			ctx, cancel := context.WithTimeout(context.Background(), budget)
+			defer cancel()
+
+			start := time.Now()
+			streams, ttl := searchStreams(ctx, testConfig, "series", testImdb+":1:2", "")
+
+			if elapsed := time.Since(start); elapsed > budget+time.Second {
+				t.Errorf("answered after %v, the budget is %v", elapsed, budget)
+			}
+			if len(streams.Streams) != 0 {
+				t.Errorf("%d streams, want none", len(streams.Streams))
+			}
+			if ttl <= 0 || ttl > partialStreamsTTL {
+				t.Errorf("ttl %v, want a partial answer ttl", ttl)
+			}
+			if test.blockSearch && host.reads.Load() != 0 {
+				t.Errorf("%d torrents read after the deadline", host.reads.Load())
+			}
+		})
+	}
+}
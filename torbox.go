@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types/tb"
+)
+
+const tbBaseURL = "https://api.torbox.app/v1/api"
+
+type torBox struct {
+	apiKey  string
+	baseURL string
+}
+
+func newTorBox(apiKey string) *torBox {
+	return &torBox{apiKey: apiKey, baseURL: tbBaseURL}
+}
+
+func (t *torBox) bearer() string {
+	return fmt.Sprintf("Bearer %s", t.apiKey)
+}
+
+func (t *torBox) CheckCached(ctx context.Context, hashes []string) (map[string]bool, error) {
+	cached := make(map[string]bool, len(hashes))
+	if len(hashes) == 0 {
+		return cached, nil
+	}
+
+	params := url.Values{"hash": {strings.Join(hashes, ",")}, "format": {"object"}, "list_files": {"false"}}
+
+	var res tb.CheckCachedResponse
+	if err := debridGet(ctx, fmt.Sprintf("%s/torrents/checkcached?%s", t.baseURL, params.Encode()), t.bearer(), &res); err != nil {
+		return cached, err
+	}
+	if !res.Success {
+		return cached, fmt.Errorf("torbox: %s", res.Detail)
+	}
+
+	// only the cached hashes are part of the answer
+	for _, hash := range hashes {
+		_, ok := res.Data[strings.ToLower(hash)]
+		cached[strings.ToLower(hash)] = ok
+	}
+	return cached, nil
+}
+
+// FindTorrent reuses a torrent of the account, TorBox keeps every file of it.
+func (t *torBox) FindTorrent(ctx context.Context, infoHash string, target debridTarget) (string, error) {
+	var res tb.MyListAllResponse
+	if err := debridGet(ctx, fmt.Sprintf("%s/torrents/mylist?bypass_cache=true", t.baseURL), t.bearer(), &res); err != nil {
+		return "", err
+	}
+	if !res.Success {
+		return "", fmt.Errorf("torbox: %s", res.Detail)
+	}
+
+	for _, torrent := range res.Data {
+		if strings.ToLower(torrent.Hash) == infoHash && !strings.Contains(torrent.DownloadState, "error") {
+			return strconv.Itoa(torrent.ID), nil
+		}
+	}
+	return "", nil
+}
+
+func (t *torBox) AddMagnet(ctx context.Context, magnet string) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("magnet", magnet)
+	form.Close()
+
+	var res tb.CreateTorrentResponse
+	if err := debridPost(ctx, fmt.Sprintf("%s/torrents/createtorrent", t.baseURL), form.FormDataContentType(), &body, t.bearer(), &res); err != nil {
+		return "", err
+	}
+	if !res.Success {
+		return "", fmt.Errorf("torbox: %s", res.Detail)
+	}
+
+	return strconv.Itoa(res.Data.TorrentID), nil
+}
+
+// SelectFiles is a no-op, TorBox always downloads the whole torrent.
+func (t *torBox) SelectFiles(ctx context.Context, id string, target debridTarget) error {
+	return nil
+}
+
+// WaitReady returns a "torrentId:fileId" link, TorBox hands out download
+// urls per file through requestdl.
+func (t *torBox) WaitReady(ctx context.Context, id string, target debridTarget) (string, error) {
+	deadline := time.Now().Add(debridReadyTimeout)
+
+	for {
+		var res tb.MyListResponse
+		if err := debridGet(ctx, fmt.Sprintf("%s/torrents/mylist?id=%s&bypass_cache=true", t.baseURL, url.QueryEscape(id)), t.bearer(), &res); err != nil {
+			return "", err
+		}
+		if !res.Success {
+			return "", fmt.Errorf("torbox: %s", res.Detail)
+		}
+
+		if res.Data.DownloadPresent {
+			files := make([]debridFile, 0, len(res.Data.Files))
+			for _, file := range res.Data.Files {
+				files = append(files, debridFile{Name: file.Name, Size: file.Size, Link: fmt.Sprintf("%s:%d", id, file.ID)})
+			}
+
+			file, found := pickDebridFile(files, target)
+			if !found {
+				return "", errors.New("torbox: file not found in the torrent")
+			}
+			return file.Link, nil
+		}
+		if strings.Contains(res.Data.DownloadState, "error") {
+			return "", fmt.Errorf("torbox: torrent %s", res.Data.DownloadState)
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("torbox: torrent not ready (%s)", res.Data.DownloadState)
+		}
+		if !sleepContext(ctx, debridPollInterval) {
+			return "", ctx.Err()
+		}
+	}
+}
+
+func (t *torBox) Unrestrict(ctx context.Context, link string) (string, error) {
+	torrentID, fileID, found := strings.Cut(link, ":")
+	if !found {
+		return "", fmt.Errorf("torbox: invalid link %s", link)
+	}
+
+	params := url.Values{"token": {t.apiKey}, "torrent_id": {torrentID}, "file_id": {fileID}}
+
+	var res tb.RequestDlResponse
+	if err := debridGet(ctx, fmt.Sprintf("%s/torrents/requestdl?%s", t.baseURL, params.Encode()), t.bearer(), &res); err != nil {
+		return "", err
+	}
+	if !res.Success || len(res.Data) == 0 {
+		return "", fmt.Errorf("torbox: %s", res.Detail)
+	}
+	return res.Data, nil
+}
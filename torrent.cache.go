@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// torrent metadata never changes for an infohash, it is cached so the popular
+// torrents are downloaded once. Entries are found by infohash or by the
+// indexer download url.
+const defaultTorrentCacheTTL = 30 * 24 * time.Hour
+
+// TORRENT_CACHE_TTL is in hours
+func torrentCacheTTL() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("TORRENT_CACHE_TTL"))
+	if err != nil || hours <= 0 {
+		return defaultTorrentCacheTTL
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func torrentKey(infoHash string) string {
+	return "torrent:" + infoHash
+}
+
+func torrentURLKey(url string) string {
+	return "torrent-url:" + url
+}
+
+// cachedTorrentMeta looks the torrent up by infohash first, then by its
+// download url. Either can be empty.
+func cachedTorrentMeta(infoHash string, url string) (types.TorrentMeta, bool) {
+	if meta, ok := cachedTorrentByHash(infoHash); ok {
+		return meta, true
+	}
+	if url == "" {
+		return types.TorrentMeta{}, false
+	}
+
+	// the indexer hash can differ from the one of the torrent
+	var urlHash string
+	found, err := torrentCache().Get(torrentURLKey(url), &urlHash)
+	if err != nil {
+		logError("torrent cache read failed", err, "url", url)
+	}
+	if !found || err != nil || urlHash == infoHash {
+		return types.TorrentMeta{}, false
+	}
+
+	// a url entry whose torrent got evicted is a miss, it is overwritten
+	// once the torrent is read again
+	return cachedTorrentByHash(urlHash)
+}
+
+func cachedTorrentByHash(infoHash string) (types.TorrentMeta, bool) {
+	if infoHash == "" {
+		return types.TorrentMeta{}, false
+	}
+
+	var meta types.TorrentMeta
+	found, err := torrentCache().Get(torrentKey(infoHash), &meta)
+	if err != nil {
+		logError("torrent cache read failed", err, "infohash", infoHash)
+		return types.TorrentMeta{}, false
+	}
+	return meta, found
+}
+
+// storeTorrentMeta keeps the metadata of a torrent, with the url it was
+// downloaded from when there is one.
+func storeTorrentMeta(meta types.TorrentMeta, url string) {
+	if meta.InfoHash == "" {
+		return
+	}
+
+	cache := torrentCache()
+	ttl := torrentCacheTTL()
+
+	err := cache.Set(torrentKey(meta.InfoHash), meta, ttl)
+	if err == nil && url != "" {
+		err = cache.Set(torrentURLKey(url), meta.InfoHash, ttl)
+	}
+	if err != nil {
+		logError("torrent cache write failed", err, "infohash", meta.InfoHash)
+	}
+}
@@ -1,17 +1,58 @@
 package main
 
-import "github.com/anacrolix/torrent"
+import (
+	"os"
+	"strconv"
+	"sync"
 
-func TorrentClient() *torrent.Client {
-	config := torrent.NewDefaultClientConfig()
-	config.DataDir = "./temp"
-	config.ListenHost = func(network string) string { return "localhost" }
-	client, err := torrent.NewClient(config)
+	"github.com/anacrolix/torrent"
+)
 
-	if err != nil {
-		_c, _ := torrent.NewClient(nil)
-		return _c
+// the client is only used to fetch magnet metadata from the DHT, it is
+// shared by all the requests and never closed
+var (
+	torrentClient     *torrent.Client
+	torrentClientErr  error
+	torrentClientOnce sync.Once
+)
+
+// how many magnets get their metadata fetched at the same time
+const defaultMagnetConcurrency = 4
+
+// made on first use, the package is initialized before .env is loaded
+var (
+	magnetSlotsChan chan struct{}
+	magnetSlotsOnce sync.Once
+)
+
+func magnetSlots() chan struct{} {
+	magnetSlotsOnce.Do(func() {
+		magnetSlotsChan = make(chan struct{}, magnetConcurrency())
+	})
+	return magnetSlotsChan
+}
+
+func magnetConcurrency() int {
+	n, err := strconv.Atoi(os.Getenv("MAGNET_CONCURRENCY"))
+	if err != nil || n <= 0 {
+		return defaultMagnetConcurrency
 	}
+	return n
+}
+
+func TorrentClient() (*torrent.Client, error) {
+	torrentClientOnce.Do(func() {
+		config := torrent.NewDefaultClientConfig()
+		config.DataDir = "./temp"
+		config.ListenHost = func(network string) string { return "localhost" }
+		config.NoUpload = true
+		config.Seed = false
+
+		torrentClient, torrentClientErr = torrent.NewClient(config)
+		if torrentClientErr != nil {
+			torrentClient, torrentClientErr = torrent.NewClient(nil)
+		}
+	})
 
-	return client
+	return torrentClient, torrentClientErr
 }
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMagnetSlots(t *testing.T) {
+	t.Cleanup(func() { magnetSlotsOnce = sync.Once{} })
+
+	for value, want := range map[string]int{"2": 2, "": defaultMagnetConcurrency, "-1": defaultMagnetConcurrency, "many": defaultMagnetConcurrency} {
+		// as if the process had just started and loaded .env
+		magnetSlotsOnce = sync.Once{}
+		t.Setenv("MAGNET_CONCURRENCY", value)
+
+		if got := cap(magnetSlots()); got != want {
+			t.Errorf("MAGNET_CONCURRENCY=%q: %d slots, want %d", value, got, want)
+		}
+
+		// read once for the process life
+		t.Setenv("MAGNET_CONCURRENCY", "7")
+		if got := cap(magnetSlots()); got != want {
+			t.Errorf("MAGNET_CONCURRENCY=%q: %d slots after a change, want %d", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/daniwalter001/jackett_fiber/magnet"
+	"github.com/daniwalter001/jackett_fiber/types"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/sync/singleflight"
+)
+
+const torrentFetchTimeout = 15 * time.Second
+const magnetTimeout = 15 * time.Second
+
+// indexers often answer the .torrent link with a redirect to a magnet
+const maxTorrentRedirects = 5
+
+// readTorrent fills the file list of a result from the torrent cache, or from
+// its .torrent file or the DHT for magnet only results.
+func readTorrent(ctx context.Context, item types.ItemsParsed) (types.ItemsParsed, error) {
+	url := item.MagnetURI
+	isMagnet := magnet.IsMagnet(url)
+	if isMagnet {
+		// the infohash is enough to find a magnet
+		url = ""
+	}
+
+	if meta, ok := cachedTorrentMeta(item.InfoHash, url); ok {
+		return withTorrentMeta(item, meta), nil
+	}
+
+	var meta types.TorrentMeta
+	var err error
+
+	if isMagnet {
+		meta, err = fetchMagnetMeta(ctx, item.MagnetURI)
+	} else {
+		meta, err = fetchTorrentFile(ctx, item.MagnetURI)
+	}
+	if err != nil {
+		return item, upstreamError(stageTorrent, item.Title, 0, err)
+	}
+
+	// magnet fetches cache their metadata themselves
+	if !isMagnet {
+		storeTorrentMeta(meta, url)
+	}
+	return withTorrentMeta(item, meta), nil
+}
+
+// withTorrentMeta completes a result with what its torrent tells. The infohash
+// of the info dictionary wins over the one given by the indexer, .torrent
+// links often come without any.
+func withTorrentMeta(item types.ItemsParsed, meta types.TorrentMeta) types.ItemsParsed {
+	if meta.InfoHash != "" {
+		item.InfoHash = meta.InfoHash
+	}
+	for _, tracker := range meta.Trackers {
+		if !slices.Contains(item.Trackers, tracker) {
+			item.Trackers = append(item.Trackers, tracker)
+		}
+	}
+	item.TorrentData = meta.Files
+
+	if item.Size == 0 {
+		for _, file := range meta.Files {
+			item.Size += file.Size
+		}
+	}
+	return item
+}
+
+// withMagnet completes a search result with what its magnet tells, the
+// infohash and size when the indexer gave none and the trackers.
+func withMagnet(item types.ItemsParsed) types.ItemsParsed {
+	item.InfoHash = magnet.NormalizeHash(item.InfoHash)
+
+	m, err := magnet.Parse(item.MagnetURI)
+	if err != nil {
+		return item
+	}
+	if item.InfoHash == "" {
+		item.InfoHash = m.InfoHash
+	}
+	if item.Size == 0 {
+		item.Size = m.Size
+	}
+	for _, tracker := range m.Trackers {
+		if !slices.Contains(item.Trackers, tracker) {
+			item.Trackers = append(item.Trackers, tracker)
+		}
+	}
+	return item
+}
+
+// torrentSources lists where stremio finds the peers of a torrent, the
+// trackers of the torrent and the DHT.
+func torrentSources(item types.ItemsParsed) []string {
+	sources := make([]string, 0, len(item.Trackers)+1)
+	for _, tracker := range item.Trackers {
+		sources = append(sources, "tracker:"+tracker)
+	}
+	if item.InfoHash != "" {
+		sources = append(sources, "dht:"+item.InfoHash)
+	}
+	return sources
+}
+
+// parseTorrentMeta reads the bencoded .torrent content, no client needed.
+func parseTorrentMeta(data []byte) (types.TorrentMeta, error) {
+	mi, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		return types.TorrentMeta{}, err
+	}
+	return torrentMeta(mi)
+}
+
+func torrentMeta(mi *metainfo.MetaInfo) (types.TorrentMeta, error) {
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return types.TorrentMeta{}, err
+	}
+
+	meta := types.TorrentMeta{
+		InfoHash:    mi.HashInfoBytes().HexString(),
+		Name:        info.BestName(),
+		PieceLength: info.PieceLength,
+		Trackers:    mi.UpvertedAnnounceList().DistinctValues(),
+	}
+
+	// same paths and order as torrent.File.DisplayPath, file indexes stay valid
+	for _, file := range info.UpvertedFiles() {
+		meta.Files = append(meta.Files, types.TorrentFile{
+			Path: file.DisplayPath(&info),
+			Size: file.Length,
+		})
+	}
+	return meta, nil
+}
+
+func fetchTorrentFile(ctx context.Context, url string) (types.TorrentMeta, error) {
+	for redirects := 0; redirects <= maxTorrentRedirects; redirects++ {
+		response := fiber.AcquireResponse()
+		request := fiber.Get(url).Timeout(timeoutFor(ctx, torrentFetchTimeout)).SetResponse(response)
+
+		status, data, errs := request.Bytes()
+		location := string(response.Header.Peek("Location"))
+		fiber.ReleaseResponse(response)
+
+		if len(errs) > 0 {
+			return types.TorrentMeta{}, errs[0]
+		}
+
+		if status >= 300 && status < 400 && location != "" {
+			if magnet.IsMagnet(location) {
+				return fetchMagnetMeta(ctx, location)
+			}
+			url = location
+			continue
+		}
+
+		if status >= 400 {
+			return types.TorrentMeta{}, fmt.Errorf("status %d", status)
+		}
+
+		return parseTorrentMeta(data)
+	}
+
+	return types.TorrentMeta{}, errors.New("too many redirects")
+}
+
+// magnet fetches outlive the requests waiting for them, by infohash
+var magnetFlights singleflight.Group
+
+// downloadMagnet is replaced in tests, there are no peers to ask
+var downloadMagnet = downloadMagnetMeta
+
+// fetchMagnetMeta gets the metadata of a magnet from its peers. Peers are
+// slow to find, the fetch runs in the background on its own timeout and
+// caches what it gets: a request ending first leaves it running so that the
+// next one finds the torrent in cache. Requests for a magnet being fetched
+// join the running fetch.
+func fetchMagnetMeta(ctx context.Context, link string) (types.TorrentMeta, error) {
+	key := link
+	if m, err := magnet.Parse(link); err == nil && m.InfoHash != "" {
+		key = m.InfoHash
+	}
+
+	done := magnetFlights.DoChan(key, func() (any, error) {
+		meta, err := downloadMagnet(link)
+		if err != nil {
+			return nil, err
+		}
+		storeTorrentMeta(meta, "")
+		return meta, nil
+	})
+
+	select {
+	case result := <-done:
+		if result.Err != nil {
+			return types.TorrentMeta{}, result.Err
+		}
+		return result.Val.(types.TorrentMeta), nil
+	case <-ctx.Done():
+		return types.TorrentMeta{}, ctx.Err()
+	}
+}
+
+// downloadMagnetMeta asks the peers through the shared client. Only a few
+// run at once, the others wait for a slot until the timeout.
+func downloadMagnetMeta(link string) (types.TorrentMeta, error) {
+	client, err := TorrentClient()
+	if err != nil {
+		return types.TorrentMeta{}, err
+	}
+
+	slots := magnetSlots()
+	timeout := time.After(magnetTimeout)
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	case <-timeout:
+		return types.TorrentMeta{}, errors.New("no magnet slot available")
+	}
+
+	t, err := client.AddMagnet(link)
+	if err != nil {
+		return types.TorrentMeta{}, err
+	}
+	defer t.Drop()
+
+	select {
+	case <-t.GotInfo():
+		mi := t.Metainfo()
+		return torrentMeta(&mi)
+	case <-t.Closed():
+		return types.TorrentMeta{}, errors.New("torrent dropped")
+	case <-timeout:
+		return types.TorrentMeta{}, errors.New("metadata fetch timed out")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daniwalter001/jackett_fiber/magnet"
+	"github.com/daniwalter001/jackett_fiber/types"
+)
+
+// a fetch cut by the request deadline keeps going and fills the cache
+func TestFetchMagnetMetaBackground(t *testing.T) {
+	useTestCaches(t)
+	torrent := newTestTorrent(t, "Show S01E03 1080p", "Show.S01E03.1080p.mkv")
+	link := magnet.Build(torrent.hash, torrent.title, nil)
+
+	var downloads atomic.Int32
+	release := make(chan struct{})
+	previous := downloadMagnet
+	downloadMagnet = func(string) (types.TorrentMeta, error) {
+		downloads.Add(1)
+		<-release
+		return parseTorrentMeta(torrent.data)
+	}
+	t.Cleanup(func() { downloadMagnet = previous })
+
+	item := types.ItemsParsed{Title: torrent.title, MagnetURI: link, InfoHash: torrent.hash}
+
+	// two requests out of time while the peers are slow
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := readTorrent(ctx, item)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("request %d: error %v, want the deadline", i, err)
+		}
+	}
+	if _, ok := cachedTorrentMeta(torrent.hash, ""); ok {
+		t.Fatal("cached before the fetch ended")
+	}
+
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := cachedTorrentMeta(torrent.hash, ""); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the background fetch cached nothing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the next request is served from cache
+	read, err := readTorrent(context.Background(), item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.TorrentData) != 1 || read.TorrentData[0].Path != "Show.S01E03.1080p.mkv" {
+		t.Errorf("files %+v", read.TorrentData)
+	}
+	if downloads.Load() != 1 {
+		t.Errorf("%d downloads, want the requests to share one", downloads.Load())
+	}
+}
@@ -0,0 +1,6 @@
+package ad
+
+type AdError struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,59 @@
+package ad
+
+type InstantResponse struct {
+	Status string `json:"status,omitempty"`
+	Data   struct {
+		Magnets []struct {
+			Magnet  string `json:"magnet,omitempty"`
+			Hash    string `json:"hash,omitempty"`
+			Instant bool   `json:"instant,omitempty"`
+		} `json:"magnets,omitempty"`
+	} `json:"data,omitempty"`
+	Error *AdError `json:"error,omitempty"`
+}
+
+type UploadResponse struct {
+	Status string `json:"status,omitempty"`
+	Data   struct {
+		Magnets []struct {
+			Magnet string   `json:"magnet,omitempty"`
+			Hash   string   `json:"hash,omitempty"`
+			Name   string   `json:"name,omitempty"`
+			ID     int      `json:"id,omitempty"`
+			Ready  bool     `json:"ready,omitempty"`
+			Error  *AdError `json:"error,omitempty"`
+		} `json:"magnets,omitempty"`
+	} `json:"data,omitempty"`
+	Error *AdError `json:"error,omitempty"`
+}
+
+type StatusResponse struct {
+	Status string `json:"status,omitempty"`
+	Data   struct {
+		Magnets struct {
+			ID         int    `json:"id,omitempty"`
+			Filename   string `json:"filename,omitempty"`
+			Status     string `json:"status,omitempty"`
+			StatusCode int    `json:"statusCode,omitempty"`
+			Links      []struct {
+				Link     string `json:"link,omitempty"`
+				Filename string `json:"filename,omitempty"`
+				Size     int64  `json:"size,omitempty"`
+			} `json:"links,omitempty"`
+		} `json:"magnets,omitempty"`
+	} `json:"data,omitempty"`
+	Error *AdError `json:"error,omitempty"`
+}
+
+type ListResponse struct {
+	Status string `json:"status,omitempty"`
+	Data   struct {
+		Magnets []struct {
+			ID         int    `json:"id,omitempty"`
+			Hash       string `json:"hash,omitempty"`
+			Status     string `json:"status,omitempty"`
+			StatusCode int    `json:"statusCode,omitempty"`
+		} `json:"magnets,omitempty"`
+	} `json:"data,omitempty"`
+	Error *AdError `json:"error,omitempty"`
+}
@@ -0,0 +1,11 @@
+package ad
+
+type UnlockResponse struct {
+	Status string `json:"status,omitempty"`
+	Data   struct {
+		Link     string `json:"link,omitempty"`
+		Filename string `json:"filename,omitempty"`
+		Filesize int64  `json:"filesize,omitempty"`
+	} `json:"data,omitempty"`
+	Error *AdError `json:"error,omitempty"`
+}
@@ -0,0 +1,25 @@
+package types
+
+// UserConfig is the per-user settings blob carried (base64url encoded and
+// optionally encrypted) in the addon URL.
+type UserConfig struct {
+	DebridProvider  string   `json:"debridProvider,omitempty"`
+	DebridKey       string   `json:"debridKey,omitempty"`
+	CachedOnly      bool     `json:"cachedOnly,omitempty"`
+	Indexers        []string `json:"indexers,omitempty"`
+	Languages       []string `json:"languages,omitempty"`
+	MaxQuality      string   `json:"maxQuality,omitempty"`
+	MaxResults      int      `json:"maxResults,omitempty"`
+	SortBy          string   `json:"sortBy,omitempty"`
+	ExcludeKeywords []string `json:"excludeKeywords,omitempty"`
+	Template        string   `json:"template,omitempty"`
+
+	// ranking, weights are keyed by criterion (resolution, cached, seeders,
+	// size, language, codec, indexer) and override the server defaults
+	Weights         map[string]float64 `json:"weights,omitempty"`
+	PreferredCodec  string             `json:"preferredCodec,omitempty"`
+	PreferredSizeGB float64            `json:"preferredSizeGB,omitempty"`
+	ExcludeCam      bool               `json:"excludeCam,omitempty"`
+	MaxSizeGB       float64            `json:"maxSizeGB,omitempty"`
+	RequireLanguage string             `json:"requireLanguage,omitempty"`
+}
@@ -0,0 +1,12 @@
+package types
+
+type Indexer struct {
+	// Jackett indexer id, "all" for the aggregate
+	ID string
+	// torznab categories per stremio type, "" is the fallback
+	Categories map[string]string
+	// results kept per query, 0 means no cap
+	MaxResults int
+	// full torznab path replacing the Jackett layout (legacy OVERRIDE_API_URL)
+	Path string
+}
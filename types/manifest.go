@@ -1,13 +1,19 @@
 package types
 
 type StreamManifest struct {
-	Description string   `json:"description"`
-	ID          string   `json:"id"`
-	Logo        string   `json:"logo"`
-	Name        string   `json:"name"`
-	Resources   []string `json:"resources"`
-	IdPrefixes  []string `json:"idPrefixes"`
-	Types       []string `json:"types"`
-	Version     string   `json:"version"`
-	Catalogs    []string `json:"catalogs"`
+	Description   string                `json:"description"`
+	ID            string                `json:"id"`
+	Logo          string                `json:"logo"`
+	Name          string                `json:"name"`
+	Resources     []string              `json:"resources"`
+	IdPrefixes    []string              `json:"idPrefixes"`
+	Types         []string              `json:"types"`
+	Version       string                `json:"version"`
+	Catalogs      []string              `json:"catalogs"`
+	BehaviorHints ManifestBehaviorHints `json:"behaviorHints,omitempty"`
+}
+
+type ManifestBehaviorHints struct {
+	Configurable          bool `json:"configurable,omitempty"`
+	ConfigurationRequired bool `json:"configurationRequired,omitempty"`
 }
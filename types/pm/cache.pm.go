@@ -0,0 +1,8 @@
+package pm
+
+type CacheCheckResponse struct {
+	Status   string   `json:"status,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Response []bool   `json:"response,omitempty"`
+	Filename []string `json:"filename,omitempty"`
+}
@@ -0,0 +1,31 @@
+package pm
+
+type TransferCreateResponse struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Type    string `json:"type,omitempty"`
+}
+
+type DirectDlResponse struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	Content []struct {
+		Path       string `json:"path,omitempty"`
+		Size       int64  `json:"size,omitempty"`
+		Link       string `json:"link,omitempty"`
+		StreamLink string `json:"stream_link,omitempty"`
+	} `json:"content,omitempty"`
+}
+
+type TransferListResponse struct {
+	Status    string `json:"status,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Transfers []struct {
+		ID     string `json:"id,omitempty"`
+		Name   string `json:"name,omitempty"`
+		Status string `json:"status,omitempty"`
+		Src    string `json:"src,omitempty"`
+	} `json:"transfers,omitempty"`
+}
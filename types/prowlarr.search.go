@@ -0,0 +1,24 @@
+package types
+
+type ProwlarrSearchResult struct {
+	Guid        string `json:"guid,omitempty"`
+	IndexerID   int    `json:"indexerId,omitempty"`
+	Indexer     string `json:"indexer,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	PublishDate string `json:"publishDate,omitempty"`
+	DownloadURL string `json:"downloadUrl,omitempty"`
+	MagnetURL   string `json:"magnetUrl,omitempty"`
+	InfoHash    string `json:"infoHash,omitempty"`
+	Seeders     int    `json:"seeders,omitempty"`
+	Leechers    int    `json:"leechers,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+}
+
+type ProwlarrIndexer struct {
+	ID             int    `json:"id,omitempty"`
+	Name           string `json:"name,omitempty"`
+	DefinitionName string `json:"definitionName,omitempty"`
+	Enable         bool   `json:"enable,omitempty"`
+	Protocol       string `json:"protocol,omitempty"`
+}
@@ -0,0 +1,7 @@
+package rd
+
+type TorrentsResponse []struct {
+	ID     string `json:"id,omitempty"`
+	Hash   string `json:"hash,omitempty"`
+	Status string `json:"status,omitempty"`
+}
@@ -1,6 +1,23 @@
 package types
 
+import "time"
+
 type Server struct {
-	Host   string
-	ApiKey string
+	Host    string
+	ApiKey  string
+	Backend string
+}
+
+// ServerHealth is the pool view of a server, as shown on the admin endpoint
+type ServerHealth struct {
+	Host                string     `json:"host"`
+	Backend             string     `json:"backend"`
+	Healthy             bool       `json:"healthy"`
+	Requests            int        `json:"requests"`
+	Failures            int        `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	ErrorRate           float64    `json:"errorRate"`
+	LatencyMs           int64      `json:"latencyMs"`
+	OpenUntil           *time.Time `json:"openUntil,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
 }
@@ -1,14 +1,17 @@
 package types
 
+import "time"
+
 type StreamMeta struct {
-	Streams []TorrentStreams `json:"streams,omitempty"`
+	Streams []TorrentStreams `json:"streams"`
 }
 
+// FileIdx is 0-based, a pointer so the first file is still sent
 type TorrentStreams struct {
 	Name          string        `json:"name,omitempty"`
 	Type          string        `json:"type,omitempty"`
 	InfoHash      string        `json:"infoHash,omitempty"`
-	FileIdx       int           `json:"fileIdx,omitempty"`
+	FileIdx       *int          `json:"fileIdx,omitempty"`
 	Sources       []string      `json:"sources,omitempty"`
 	Title         string        `json:"title,omitempty"`
 	URL           string        `json:"url,omitempty"`
@@ -16,6 +19,19 @@ type TorrentStreams struct {
 }
 
 type BehaviorHints struct {
-	BingeGroup  string `json:"bingeGroup,omitempty"`
-	NotWebReady bool   `json:"notWebReady,omitempty"`
+	BingeGroup       string   `json:"bingeGroup,omitempty"`
+	NotWebReady      bool     `json:"notWebReady,omitempty"`
+	CountryWhitelist []string `json:"countryWhitelist,omitempty"`
+}
+
+// CachedStreams is a stream answer as kept in the cache. TTL is in seconds,
+// the answer is stale once Created+TTL is past.
+type CachedStreams struct {
+	Streams []TorrentStreams `json:"streams"`
+	Created time.Time        `json:"created"`
+	TTL     int64            `json:"ttl"`
+}
+
+func (c CachedStreams) Stale() bool {
+	return time.Since(c.Created) > time.Duration(c.TTL)*time.Second
 }
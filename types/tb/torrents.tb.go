@@ -0,0 +1,55 @@
+package tb
+
+type CheckCachedResponse struct {
+	Success bool   `json:"success,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	Data    map[string]struct {
+		Name string `json:"name,omitempty"`
+		Size int64  `json:"size,omitempty"`
+		Hash string `json:"hash,omitempty"`
+	} `json:"data,omitempty"`
+}
+
+type CreateTorrentResponse struct {
+	Success bool   `json:"success,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	Data    struct {
+		TorrentID int    `json:"torrent_id,omitempty"`
+		Hash      string `json:"hash,omitempty"`
+	} `json:"data,omitempty"`
+}
+
+type MyListResponse struct {
+	Success bool   `json:"success,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	Data    struct {
+		ID               int    `json:"id,omitempty"`
+		Hash             string `json:"hash,omitempty"`
+		Name             string `json:"name,omitempty"`
+		DownloadState    string `json:"download_state,omitempty"`
+		DownloadFinished bool   `json:"download_finished,omitempty"`
+		DownloadPresent  bool   `json:"download_present,omitempty"`
+		Files            []struct {
+			ID        int    `json:"id"`
+			Name      string `json:"name,omitempty"`
+			ShortName string `json:"short_name,omitempty"`
+			Size      int64  `json:"size,omitempty"`
+		} `json:"files,omitempty"`
+	} `json:"data,omitempty"`
+}
+
+type MyListAllResponse struct {
+	Success bool   `json:"success,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	Data    []struct {
+		ID            int    `json:"id,omitempty"`
+		Hash          string `json:"hash,omitempty"`
+		DownloadState string `json:"download_state,omitempty"`
+	} `json:"data,omitempty"`
+}
+
+type RequestDlResponse struct {
+	Success bool   `json:"success,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	Data    string `json:"data,omitempty"`
+}
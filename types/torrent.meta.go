@@ -0,0 +1,18 @@
+package types
+
+// TorrentFile is a file listed in a torrent, Path is relative to the torrent
+// root and uses "/" separators.
+type TorrentFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// TorrentMeta is what is read from a torrent metainfo, files are in the
+// torrent order.
+type TorrentMeta struct {
+	InfoHash    string        `json:"infoHash"`
+	Name        string        `json:"name"`
+	PieceLength int64         `json:"pieceLength"`
+	Files       []TorrentFile `json:"files"`
+	Trackers    []string      `json:"trackers,omitempty"`
+}
@@ -0,0 +1,45 @@
+package types
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+)
+
+type TorznabCaps struct {
+	XMLName   xml.Name `xml:"caps"`
+	Searching struct {
+		Search      TorznabSearchCaps `xml:"search"`
+		TvSearch    TorznabSearchCaps `xml:"tv-search"`
+		MovieSearch TorznabSearchCaps `xml:"movie-search"`
+	} `xml:"searching"`
+}
+
+type TorznabSearchCaps struct {
+	Available       string `xml:"available,attr"`
+	SupportedParams string `xml:"supportedParams,attr"`
+}
+
+// Supports reports whether the search mode is available with all the params
+func (c TorznabSearchCaps) Supports(params ...string) bool {
+	if c.Available != "yes" {
+		return false
+	}
+	supported := strings.Split(strings.ToLower(c.SupportedParams), ",")
+	for _, param := range params {
+		if !slices.Contains(supported, param) {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchQuery is a torznab search, either free text (t=search) or ID based
+// (t=tvsearch / t=movie).
+type SearchQuery struct {
+	Type    string
+	Query   string
+	ImdbID  string
+	Season  int
+	Episode int
+}
@@ -1,21 +1,18 @@
 package main
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
-	"encoding/xml"
 	"fmt"
-	"math/rand"
 	"os"
 	"strings"
 	"time"
 
-	"github.com/anacrolix/torrent"
 	"github.com/daniwalter001/jackett_fiber/types"
 	"github.com/gofiber/fiber/v2"
 )
 
-func getMeta(id string, type_ string) (string, string) {
+func getMeta(ctx context.Context, id string, type_ string) (string, string, error) {
 
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetEscapeHTML(false)
@@ -24,18 +21,18 @@ func getMeta(id string, type_ string) (string, string) {
 	// api := "https://v3-cinemeta.strem.io/meta/" + type_ + "/" + splitedId[0] + ".json"
 	api := "https://cinemeta-live.strem.io/meta/" + type_ + "/" + splitedId[0] + ".json"
 	fmt.Println(api)
-	request := fiber.Get(api)
+	request := fiber.Get(api).Timeout(timeoutFor(ctx, metaTimeout))
 
-	status, data, err := request.Bytes()
+	status, data, errs := request.Bytes()
 
-	if err != nil {
-		panic(err)
+	if len(errs) > 0 {
+		return "", "", upstreamError(stageMeta, "cinemeta", 0, errs[0])
 	}
 
 	fmt.Printf("Status code: %d\n", status)
 
 	if status >= 400 {
-		return "", ""
+		return "", "", upstreamError(stageMeta, "cinemeta", status, nil)
 	}
 
 	var res types.IMDBMeta
@@ -43,38 +40,46 @@ func getMeta(id string, type_ string) (string, string) {
 	jsonErr := json.Unmarshal(data, &res)
 
 	if jsonErr != nil {
-		return "", ""
+		return "", "", upstreamError(stageMeta, "cinemeta", 0, jsonErr)
+	}
+
+	if res.Meta.Name == nil {
+		return "", "", upstreamError(stageMeta, "cinemeta", 0, fmt.Errorf("no meta for %s", id))
 	}
 
 	var year string
 
 	if res.Meta.Year != nil {
 		year = *res.Meta.Year
-	} else if res.Meta.ReleaseInfo != nil {
+	} else if res.Meta.ReleaseInfo != nil && len(*res.Meta.ReleaseInfo) >= 4 {
 		year = (*res.Meta.ReleaseInfo)[:4]
 	} else {
 		year = ""
 	}
 
-	return *res.Meta.Name, year
+	return *res.Meta.Name, year, nil
 }
 
-func getImdbFromKitsu(id string) []string {
+func getImdbFromKitsu(ctx context.Context, id string) ([]string, error) {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetEscapeHTML(false)
 
 	splitedId := strings.Split(id, ":")
+	if len(splitedId) < 2 {
+		return nil, upstreamError(stageMeta, "kitsu", 0, fmt.Errorf("invalid id %s", id))
+	}
+
 	api := "https://anime-kitsu.strem.fun/meta/anime/" + splitedId[0] + ":" + splitedId[1] + ".json"
-	request := fiber.Get(api)
-	status, data, err := request.Bytes()
-	if err != nil {
-		panic(err)
+	request := fiber.Get(api).Timeout(timeoutFor(ctx, metaTimeout))
+	status, data, errs := request.Bytes()
+	if len(errs) > 0 {
+		return nil, upstreamError(stageMeta, "kitsu", 0, errs[0])
 	}
 
 	fmt.Printf("Status code: %d\n", status)
 
 	if status >= 400 {
-		return make([]string, 0)
+		return nil, upstreamError(stageMeta, "kitsu", status, nil)
 	}
 
 	var res types.KitsuMeta
@@ -82,7 +87,7 @@ func getImdbFromKitsu(id string) []string {
 	jsonErr := json.Unmarshal(data, &res)
 
 	if jsonErr != nil {
-		panic(jsonErr)
+		return nil, upstreamError(stageMeta, "kitsu", 0, jsonErr)
 	}
 
 	imdb := res.Meta.ImdbID
@@ -121,188 +126,53 @@ func getImdbFromKitsu(id string) []string {
 
 	resArray = append(resArray, imdb, fmt.Sprint(meta.ImdbSeason), fmt.Sprint(meta.ImdbEpisode), fmt.Sprint(meta.Season), fmt.Sprint(e), abs)
 
-	return resArray
+	return resArray, nil
 
 }
 
-func fetchTorrent(query string, type_ string) []types.ItemsParsed {
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetEscapeHTML(false)
-
-	servers := getServers()
-	randomInt := rand.Intn(len(servers))
-	host := servers[randomInt].Host
-	apiKey := servers[randomInt].ApiKey
-	//
-	category := "5000"
-	if type_ == "movie" {
-		category = "2000"
-	}
-	query = removeAccents(strings.ReplaceAll(query, " ", "+"))
-
-	override := os.Getenv("OVERRIDE_API_URL")
-	api := fmt.Sprintf("%s/api/v2.0/indexers/yggtorrent/results/torznab/api?cache=false&cat=%s&apikey=%s&q=%s", host, category, apiKey, query)
-
-	if override != "" {
-		api = fmt.Sprintf("%s%s&apikey=%s&q=%s", host, override, apiKey, query)
-	}
-
-	fmt.Println(api)
-
-	request := fiber.Get(api)
+// how many hosts a query is tried on before giving up
+const searchAttempts = 3
 
-	status, data, err := request.Bytes()
-	if err != nil {
-		panic(err)
-	}
-	fmt.Printf("Status code: %d\n", status)
-	if status >= 400 {
-		return make([]types.ItemsParsed, 0)
-	}
-
-	var res types.JackettRssReponse
-
-	xmlErr := xml.Unmarshal(data, &res)
-
-	if xmlErr != nil {
-		panic(xmlErr)
-	}
+// fetchTorrent runs a query on the hosts of the pool, the next one is tried
+// when a host fails.
+func fetchTorrent(ctx context.Context, query types.SearchQuery, type_ string, indexer types.Indexer) []types.ItemsParsed {
+	var tried []string
 
-	items := res.Channel.Item
-	var parsedItems []types.ItemsParsed
-	for i := 0; i < len(items); i++ {
-		var a types.ItemsParsed
-		a.Title = items[i].Title
-		a.Link = items[i].Enclosure.URL
-		a.Tracker = items[i].Jackettindexer.Text
-		a.MagnetURI = items[i].Link
-		attr := items[i].Attr
-		for ii := 0; ii < len(attr); ii++ {
-			if attr[ii].Name == "seeders" {
-				a.Seeders = attr[ii].Value
-			}
-			if attr[ii].Name == "peers" {
-				a.Peers = attr[ii].Value
-			}
+	for attempt := 0; attempt < searchAttempts && ctx.Err() == nil; attempt++ {
+		server, ok := serverPoolInstance().pick(tried)
+		if !ok {
+			break
 		}
-		parsedItems = append(parsedItems, a)
-	}
-
-	// fmt.Println(PrettyPrint(parsedItems))
-
-	return parsedItems
-}
-
-func readTorrent(item types.ItemsParsed) types.ItemsParsed {
-	url := item.MagnetURI
-	c := TorrentClient()
-	defer c.Close()
-
-	fileName := strings.ReplaceAll((strings.Split(url, "&file="))[len(strings.Split(url, "&file="))-1], "+", "_") + ".torrent"
+		tried = append(tried, server.Host)
 
-	if len(fileName) > 100 {
-		fileName = base64.StdEncoding.EncodeToString([]byte(fileName)) + ".torrent"
-
-		fileName = fileName[len(fileName)-100:]
-	}
-	// fmt.Printf("Name: %s\n", fileName)
-	file, errFile := os.Create(fmt.Sprintf("./temp/%s", fileName))
-
-	if errFile != nil {
-		fmt.Println(errFile)
-		fmt.Printf("1Removing...%s\n", file.Name())
-		os.Remove(file.Name())
-		return item
-	}
-
-	request := fiber.Get(url).Timeout(15 * time.Second)
-
-	status, data, err := request.Bytes()
-
-	if status >= 400 {
-		// fmt.Printf("1.5Removing...%d %s\n", status, file.Name())
-		os.Remove(file.Name())
-		return item
-	}
-
-	if err != nil {
-		fmt.Printf("%s\n", err)
-		// fmt.Printf("2Removing...%s\n", file.Name())
-		os.Remove(file.Name())
-		return item
-	}
-
-	if err != nil {
-		fmt.Println(err)
-		// fmt.Printf("3Removing...%s\n", file.Name())
-		os.Remove(file.Name())
-		return item
-	}
-
-	fileError := os.WriteFile(file.Name(), data, 0666)
-
-	if fileError != nil {
-		fmt.Println(fileError)
-		fmt.Printf("4Removing...%s\n", file.Name())
-		os.Remove(file.Name())
-		return item
-	}
-
-	t, addErr := c.AddTorrentFromFile(file.Name())
-	// <-t.GotInfo()
-
-	if addErr != nil {
-		fmt.Printf("Err: %s\n", addErr)
-		fmt.Printf("5Removing...%s\n", file.Name())
-		os.Remove(file.Name())
-		return item
-	}
-	// fmt.Printf("6Removing...%s\n", file.Name())
-	os.Remove(file.Name())
-
-	var files []torrent.File
-
-	for i := 0; i < len(t.Files()); i++ {
-		file := t.Files()[i]
-		files = append(files, *file)
+		items, err := searchHost(ctx, server, query, type_, indexer)
+		if err == nil {
+			return items
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		logError("search failed", err, "host", server.Host, "indexer", indexer.ID, "attempt", attempt+1)
 	}
-	// fmt.Println(PrettyPrint(files))
-	item.TorrentData = files
-
-	return item
 
+	return make([]types.ItemsParsed, 0)
 }
 
-func readTorrentFromMagnet(item types.ItemsParsed) types.ItemsParsed {
-
-	c := TorrentClient()
-
-	t, addErr := c.AddMagnet(item.MagnetURI)
-
-	if addErr != nil {
-		fmt.Printf("ErrMagnet: %s\n", addErr)
-		return item
+// searchHost runs a query on one host and reports how it went to the pool.
+func searchHost(ctx context.Context, server types.Server, query types.SearchQuery, type_ string, indexer types.Indexer) ([]types.ItemsParsed, error) {
+	start := time.Now()
+	items, err := newSearchBackend(server).Search(ctx, query, type_, indexer)
+	if err != nil && ctx.Err() != nil {
+		// cut by the request budget, the host is not to blame
+		logError("search cut by the deadline", err, "host", server.Host, "indexer", indexer.ID)
+		return nil, err
 	}
-
-	ed := make(chan string, 1)
-	go func() {
-		<-t.GotInfo()
-		ed <- "done"
-	}()
-
-	select {
-	case <-time.After(15 * time.Second):
-		return item
-	case res := <-ed:
-		if res == "done" {
-			var files []torrent.File
-			for i := 0; i < len(t.Files()); i++ {
-				file := t.Files()[i]
-				files = append(files, *file)
-			}
-			item.TorrentData = files
-		}
-		return item
+	// an indexer failure still means the host answered
+	hostErr := err
+	if !hostFailure(err) {
+		hostErr = nil
 	}
+	serverPoolInstance().report(server.Host, time.Since(start), hostErr)
 
+	return items, err
 }